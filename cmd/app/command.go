@@ -0,0 +1,174 @@
+package app
+
+import (
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/cmd/app/describe"
+	"github.com/giantswarm/apptestctl/cmd/app/install"
+	"github.com/giantswarm/apptestctl/cmd/app/logs"
+	"github.com/giantswarm/apptestctl/cmd/app/test"
+	"github.com/giantswarm/apptestctl/cmd/app/upgradetest"
+	"github.com/giantswarm/apptestctl/cmd/app/wait"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+)
+
+const (
+	name        = "app"
+	description = "Manages App CRs in the target cluster."
+)
+
+type Config struct {
+	Cluster *cluster.Flag
+	Logger  micrologger.Logger
+	Stderr  io.Writer
+	Stdin   io.Reader
+	Stdout  io.Writer
+}
+
+func New(config Config) (*cobra.Command, error) {
+	if config.Cluster == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Cluster must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Stderr == nil {
+		config.Stderr = os.Stderr
+	}
+	if config.Stdin == nil {
+		config.Stdin = os.Stdin
+	}
+	if config.Stdout == nil {
+		config.Stdout = os.Stdout
+	}
+
+	var err error
+
+	var describeCmd *cobra.Command
+	{
+		c := describe.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		describeCmd, err = describe.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var installCmd *cobra.Command
+	{
+		c := install.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		installCmd, err = install.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var logsCmd *cobra.Command
+	{
+		c := logs.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		logsCmd, err = logs.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var testCmd *cobra.Command
+	{
+		c := test.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		testCmd, err = test.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var upgradeTestCmd *cobra.Command
+	{
+		c := upgradetest.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		upgradeTestCmd, err = upgradetest.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var waitCmd *cobra.Command
+	{
+		c := wait.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		waitCmd, err = wait.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	f := &flag{}
+
+	r := &runner{
+		flag:   f,
+		logger: config.Logger,
+		stderr: config.Stderr,
+		stdout: config.Stdout,
+	}
+
+	c := &cobra.Command{
+		Use:   name,
+		Short: description,
+		Long:  description,
+		RunE:  r.Run,
+	}
+
+	f.Init(c)
+
+	c.AddCommand(describeCmd)
+	c.AddCommand(installCmd)
+	c.AddCommand(logsCmd)
+	c.AddCommand(testCmd)
+	c.AddCommand(upgradeTestCmd)
+	c.AddCommand(waitCmd)
+
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package describe
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	appCRNamespace = "app-cr-namespace"
+	events         = "events"
+	logLevel       = "log-level"
+)
+
+type flag struct {
+	AppCRNamespace string
+	Events         int
+	LogLevel       string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppCRNamespace, appCRNamespace, metav1.NamespaceDefault, "Namespace of the App CR")
+	cmd.Flags().IntVar(&f.Events, events, 5, "Number of recent events shown per pod")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+}
+
+func (f *flag) Validate() error {
+	if f.AppCRNamespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", appCRNamespace)
+	}
+	if f.Events < 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must not be negative", events)
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
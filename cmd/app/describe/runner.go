@@ -0,0 +1,468 @@
+package describe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	r.cluster.DefaultAppCRNamespace(cmd, appCRNamespace, &r.flag.AppCRNamespace)
+
+	name := args[0]
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var app v1alpha1.App
+	err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.flag.AppCRNamespace, Name: name}, &app)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(notFoundError, "app CR '%s/%s' not found", r.flag.AppCRNamespace, name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	w := tabwriter.NewWriter(r.stdout, 0, 8, 2, ' ', 0)
+
+	err = r.describeApp(ctx, w, k8sClients, app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.describeConfig(ctx, w, k8sClients, app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	chart, err := r.describeChart(ctx, w, k8sClients, app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// chart-operator names the release after the Chart CR and deploys it to
+	// the Chart CR's namespace. The App CR is used as long as app-operator
+	// did not create the Chart CR yet.
+	releaseName := app.Name
+	releaseNamespace := app.Spec.Namespace
+	if chart != nil {
+		releaseName = chart.Spec.Name
+		releaseNamespace = chart.Spec.Namespace
+	}
+
+	latest, err := r.describeRelease(ctx, w, k8sClients, releaseName, releaseNamespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.describeWorkloads(ctx, w, k8sClients, latest)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) describeApp(ctx context.Context, w io.Writer, k8sClients k8sclient.Interface, app v1alpha1.App) error {
+	catalogNamespace := app.Spec.CatalogNamespace
+	if catalogNamespace == "" {
+		catalogNamespace = metav1.NamespaceDefault
+	}
+
+	_, _ = fmt.Fprintf(w, "App:\t%s/%s\n", app.Namespace, app.Name)
+	_, _ = fmt.Fprintf(w, "  App name:\t%s\n", app.Spec.Name)
+	_, _ = fmt.Fprintf(w, "  Target namespace:\t%s\n", app.Spec.Namespace)
+	_, _ = fmt.Fprintf(w, "  Desired version:\t%s\n", app.Spec.Version)
+	_, _ = fmt.Fprintf(w, "  Deployed version:\t%s\n", valueOrNone(app.Status.Version))
+	_, _ = fmt.Fprintf(w, "  Release status:\t%s\n", valueOrNone(app.Status.Release.Status))
+	if app.Status.Release.Reason != "" {
+		_, _ = fmt.Fprintf(w, "  Release reason:\t%s\n", app.Status.Release.Reason)
+	}
+
+	var catalog v1alpha1.Catalog
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: catalogNamespace, Name: app.Spec.Catalog}, &catalog)
+	if apierrors.IsNotFound(err) {
+		_, _ = fmt.Fprintf(w, "  Catalog:\t%s/%s (not found)\n", catalogNamespace, app.Spec.Catalog)
+		_, _ = fmt.Fprintln(w)
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var urls []string
+	for _, repo := range catalog.Spec.Repositories {
+		urls = append(urls, fmt.Sprintf("%s %s", repo.Type, repo.URL))
+	}
+	if len(urls) == 0 {
+		urls = append(urls, fmt.Sprintf("%s %s", catalog.Spec.Storage.Type, catalog.Spec.Storage.URL))
+	}
+
+	_, _ = fmt.Fprintf(w, "  Catalog:\t%s/%s (%s)\n", catalogNamespace, app.Spec.Catalog, strings.Join(urls, ", "))
+
+	var entries v1alpha1.AppCatalogEntryList
+	err = k8sClients.CtrlClient().List(ctx, &entries,
+		client.InNamespace(catalogNamespace),
+		client.MatchingLabels{label.CatalogName: app.Spec.Catalog},
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	entry := "not found, app-operator cannot resolve the version"
+	for _, e := range entries.Items {
+		if e.Spec.AppName == app.Spec.Name && e.Spec.Version == app.Spec.Version {
+			entry = e.Name
+			break
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "  AppCatalogEntry:\t%s\n", entry)
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
+
+func (r *runner) describeConfig(ctx context.Context, w io.Writer, k8sClients k8sclient.Interface, app v1alpha1.App) error {
+	type config struct {
+		Source    string
+		Kind      string
+		Name      string
+		Namespace string
+	}
+
+	configs := []config{
+		{Source: "catalog/cluster", Kind: "ConfigMap", Name: app.Spec.Config.ConfigMap.Name, Namespace: app.Spec.Config.ConfigMap.Namespace},
+		{Source: "catalog/cluster", Kind: "Secret", Name: app.Spec.Config.Secret.Name, Namespace: app.Spec.Config.Secret.Namespace},
+	}
+	for _, extra := range app.Spec.ExtraConfigs {
+		kind := "ConfigMap"
+		if strings.EqualFold(extra.Kind, "secret") {
+			kind = "Secret"
+		}
+
+		configs = append(configs, config{Source: fmt.Sprintf("extra (priority %d)", extra.Priority), Kind: kind, Name: extra.Name, Namespace: extra.Namespace})
+	}
+	configs = append(configs,
+		config{Source: "user", Kind: "ConfigMap", Name: app.Spec.UserConfig.ConfigMap.Name, Namespace: app.Spec.UserConfig.ConfigMap.Namespace},
+		config{Source: "user", Kind: "Secret", Name: app.Spec.UserConfig.Secret.Name, Namespace: app.Spec.UserConfig.Secret.Namespace},
+	)
+
+	_, _ = fmt.Fprintln(w, "Config:")
+
+	var found bool
+	for _, c := range configs {
+		if c.Name == "" {
+			continue
+		}
+		found = true
+
+		var err error
+		if c.Kind == "Secret" {
+			_, err = k8sClients.K8sClient().CoreV1().Secrets(c.Namespace).Get(ctx, c.Name, metav1.GetOptions{})
+		} else {
+			_, err = k8sClients.K8sClient().CoreV1().ConfigMaps(c.Namespace).Get(ctx, c.Name, metav1.GetOptions{})
+		}
+
+		state := "found"
+		if apierrors.IsNotFound(err) {
+			state = "missing"
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_, _ = fmt.Fprintf(w, "  %s\t%s %s/%s\t%s\n", c.Source, c.Kind, c.Namespace, c.Name, state)
+	}
+
+	if !found {
+		_, _ = fmt.Fprintln(w, "  <none>")
+	}
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
+
+func (r *runner) describeChart(ctx context.Context, w io.Writer, k8sClients k8sclient.Interface, app v1alpha1.App) (*v1alpha1.Chart, error) {
+	var chart v1alpha1.Chart
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: key.Namespace(r.cluster.Instance), Name: app.Name}, &chart)
+	if apierrors.IsNotFound(err) {
+		_, _ = fmt.Fprintf(w, "Chart:\t%s/%s (not found)\n", key.Namespace(r.cluster.Instance), app.Name)
+		_, _ = fmt.Fprintln(w)
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	revision := "<none>"
+	if chart.Status.Release.Revision != nil {
+		revision = fmt.Sprintf("%d", *chart.Status.Release.Revision)
+	}
+
+	_, _ = fmt.Fprintf(w, "Chart:\t%s/%s\n", chart.Namespace, chart.Name)
+	_, _ = fmt.Fprintf(w, "  Tarball:\t%s\n", chart.Spec.TarballURL)
+	_, _ = fmt.Fprintf(w, "  Version:\t%s\n", valueOrNone(chart.Status.Version))
+	_, _ = fmt.Fprintf(w, "  Release status:\t%s\n", valueOrNone(chart.Status.Release.Status))
+	_, _ = fmt.Fprintf(w, "  Release revision:\t%s\n", revision)
+	if chart.Status.Reason != "" {
+		_, _ = fmt.Fprintf(w, "  Reason:\t%s\n", chart.Status.Reason)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	return &chart, nil
+}
+
+// describeRelease prints the revision history stored by Helm in the release
+// Secrets and returns the latest revision.
+func (r *runner) describeRelease(ctx context.Context, w io.Writer, k8sClients k8sclient.Interface, name, namespace string) (*release.Release, error) {
+	_, _ = fmt.Fprintf(w, "Release:\t%s/%s\n", namespace, name)
+
+	secrets := driver.NewSecrets(k8sClients.K8sClient().CoreV1().Secrets(namespace))
+	releases, err := secrets.Query(map[string]string{"name": name, "owner": "helm"})
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		_, _ = fmt.Fprintln(w, "  <none>")
+		_, _ = fmt.Fprintln(w)
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version < releases[j].Version
+	})
+
+	_, _ = fmt.Fprintln(w, "  REVISION\tUPDATED\tSTATUS\tCHART\tDESCRIPTION")
+	for _, rel := range releases {
+		chart := "<unknown>"
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			chart = fmt.Sprintf("%s-%s", rel.Chart.Metadata.Name, rel.Chart.Metadata.Version)
+		}
+
+		var updated string
+		var status release.Status
+		var description string
+		if rel.Info != nil {
+			updated = rel.Info.LastDeployed.Format(time.RFC3339)
+			status = rel.Info.Status
+			description = rel.Info.Description
+		}
+
+		_, _ = fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\n", rel.Version, updated, status, chart, description)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	return releases[len(releases)-1], nil
+}
+
+func (r *runner) describeWorkloads(ctx context.Context, w io.Writer, k8sClients k8sclient.Interface, latest *release.Release) error {
+	_, _ = fmt.Fprintln(w, "Workloads:")
+
+	if latest == nil {
+		_, _ = fmt.Fprintln(w, "  <none>")
+		return nil
+	}
+
+	workloads, err := workload.FromManifest(latest.Manifest, latest.Namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(workloads) == 0 {
+		_, _ = fmt.Fprintln(w, "  <none>")
+		return nil
+	}
+
+	for _, wl := range workloads {
+		_, _ = fmt.Fprintf(w, "  %s\n", wl)
+
+		pods, err := workload.Pods(ctx, k8sClients.K8sClient(), wl)
+		if apierrors.IsNotFound(err) {
+			_, _ = fmt.Fprintln(w, "    not found")
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if len(pods) == 0 {
+			_, _ = fmt.Fprintln(w, "    no pods")
+			continue
+		}
+
+		_, _ = fmt.Fprintln(w, "    POD\tREADY\tPHASE\tRESTARTS\tAGE")
+		for _, pod := range pods {
+			ready, restarts := containerReadiness(pod)
+			_, _ = fmt.Fprintf(w, "    %s\t%s\t%s\t%d\t%s\n", pod.Name, ready, pod.Status.Phase, restarts, duration.HumanDuration(time.Since(pod.CreationTimestamp.Time)))
+		}
+
+		for _, pod := range pods {
+			err = r.describeEvents(ctx, w, k8sClients, pod)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) describeEvents(ctx context.Context, w io.Writer, k8sClients k8sclient.Interface, pod corev1.Pod) error {
+	if r.flag.Events == 0 {
+		return nil
+	}
+
+	list, err := k8sClients.K8sClient().CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.kind=Pod,involvedObject.name=%s", pod.Name),
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(list.Items) == 0 {
+		return nil
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return eventTime(list.Items[i]).Before(eventTime(list.Items[j]))
+	})
+
+	items := list.Items
+	if len(items) > r.flag.Events {
+		items = items[len(items)-r.flag.Events:]
+	}
+
+	_, _ = fmt.Fprintf(w, "    Events of %s:\n", pod.Name)
+	for _, e := range items {
+		_, _ = fmt.Fprintf(w, "      %s\t%s\t%s\t%s\n", duration.HumanDuration(time.Since(eventTime(e))), e.Type, e.Reason, strings.TrimSpace(e.Message))
+	}
+
+	return nil
+}
+
+func containerReadiness(pod corev1.Pod) (string, int32) {
+	var ready int
+	var restarts int32
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Ready {
+			ready++
+		}
+		restarts += s.RestartCount
+	}
+
+	return fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)), restarts
+}
+
+func eventTime(e corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+
+	return e.CreationTimestamp.Time
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return s
+}
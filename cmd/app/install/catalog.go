@@ -0,0 +1,72 @@
+package install
+
+import (
+	"context"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/metadata"
+)
+
+// ensureCatalog creates the Catalog CR the app is installed from when it does
+// not exist yet and --catalog-url is set.
+func (r *runner) ensureCatalog(ctx context.Context, k8sClients k8sclient.Interface) error {
+	namespace := key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced)
+
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: namespace, Name: r.flag.Catalog}, &v1alpha1.Catalog{})
+	if err == nil {
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	if r.flag.CatalogURL == "" {
+		return microerror.Maskf(notFoundError, "catalog '%s/%s' not found, use --%s to create it", namespace, r.flag.Catalog, catalogURL)
+	}
+
+	catalogCR := &v1alpha1.Catalog{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.flag.Catalog,
+			Namespace: namespace,
+			Labels: map[string]string{
+				label.AppOperatorVersion: key.UniqueAppOperatorVersion,
+			},
+		},
+		Spec: v1alpha1.CatalogSpec{
+			Description: r.flag.Catalog,
+			Title:       r.flag.Catalog,
+			// Storage is deprecated in favour of Repositories but still
+			// set for older app-operator versions.
+			Storage: v1alpha1.CatalogSpecStorage{
+				Type: "helm",
+				URL:  r.flag.CatalogURL,
+			},
+			Repositories: []v1alpha1.CatalogSpecRepository{
+				{
+					Type: "helm",
+					URL:  r.flag.CatalogURL,
+				},
+			},
+		},
+	}
+
+	metadata.Set(catalogCR, key.Labels(r.cluster.Instance))
+
+	r.logger.Debugf(ctx, "creating %#q catalog cr", r.flag.Catalog)
+
+	err = k8sClients.CtrlClient().Create(ctx, catalogCR)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "created %#q catalog cr", r.flag.Catalog)
+
+	return nil
+}
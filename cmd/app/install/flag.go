@@ -0,0 +1,84 @@
+package install
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	appCRName      = "app-cr-name"
+	appCRNamespace = "app-cr-namespace"
+	catalog        = "catalog"
+	catalogURL     = "catalog-url"
+	logLevel       = "log-level"
+	namespace      = "namespace"
+	secretValues   = "secret-values"
+	timeout        = "timeout"
+	values         = "values"
+	version        = "version"
+	wait           = "wait"
+
+	workloadCluster = "workload-cluster"
+)
+
+type flag struct {
+	AppCRName      string
+	AppCRNamespace string
+	Catalog        string
+	CatalogURL     string
+	LogLevel       string
+	Namespace      string
+	SecretValues   string
+	Timeout        time.Duration
+	Values         string
+	Version        string
+	Wait           bool
+
+	WorkloadCluster string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppCRName, appCRName, "", "Name of the App CR, defaults to the app name")
+	cmd.Flags().StringVar(&f.AppCRNamespace, appCRNamespace, metav1.NamespaceDefault, "Namespace of the App CR and its user values")
+	cmd.Flags().StringVarP(&f.Catalog, catalog, "c", "", "Catalog the app is installed from, defaults to the chartmuseum catalog created by bootstrap")
+	cmd.Flags().StringVar(&f.CatalogURL, catalogURL, "", "Helm repository URL used to create the catalog when it does not exist yet")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().StringVarP(&f.Namespace, namespace, "n", metav1.NamespaceDefault, "Namespace the app is deployed to")
+	cmd.Flags().StringVar(&f.SecretValues, secretValues, "", "Path to a YAML file with user values stored in a Secret")
+	cmd.Flags().DurationVar(&f.Timeout, timeout, 10*time.Minute, "Maximum time to wait for the release when --wait is set")
+	cmd.Flags().StringVarP(&f.Values, values, "f", "", "Path to a YAML file with user values stored in a ConfigMap")
+	cmd.Flags().StringVar(&f.Version, version, "", "Version of the app, defaults to the latest version in the catalog")
+	cmd.Flags().BoolVarP(&f.Wait, wait, "w", false, "Wait for the release to be deployed")
+
+	cmd.Flags().StringVar(&f.WorkloadCluster, workloadCluster, "", "Workload cluster set up by bootstrap --workload-kubeconfig to deploy the app into. The App CR namespace defaults to the cluster namespace.")
+}
+
+func (f *flag) Validate() error {
+	if f.AppCRNamespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", appCRNamespace)
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", namespace)
+	}
+	if f.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be positive", timeout)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
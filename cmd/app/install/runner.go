@@ -0,0 +1,374 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/metadata"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	r.cluster.DefaultAppCRNamespace(cmd, appCRNamespace, &r.flag.AppCRNamespace)
+
+	if r.flag.Catalog == "" {
+		r.flag.Catalog = key.ChartMuseumCatalogName(r.cluster.Instance)
+	}
+
+	name := args[0]
+
+	// App CRs of workload clusters live in the cluster namespace of the
+	// management cluster, like on real installations.
+	if r.flag.WorkloadCluster != "" && !cmd.Flags().Changed(appCRNamespace) {
+		r.flag.AppCRNamespace = key.WorkloadClusterNamespace(r.flag.WorkloadCluster)
+	}
+
+	crName := r.flag.AppCRName
+	if crName == "" {
+		crName = name
+	}
+
+	valuesYAML, err := readValues(r.flag.Values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	secretValuesYAML, err := readValues(r.flag.SecretValues)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Installing over an existing App CR would silently keep its old spec.
+	err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.flag.AppCRNamespace, Name: crName}, &v1alpha1.App{})
+	if err == nil {
+		return microerror.Maskf(alreadyExistsError, "app CR '%s/%s' already exists", r.flag.AppCRNamespace, crName)
+	} else if !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	err = r.ensureCatalog(ctx, k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	version := r.flag.Version
+	if version == "" {
+		version, err = r.latestVersion(ctx, k8sClients, name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = r.createApp(ctx, k8sClients, crName, name, version, valuesYAML, secretValuesYAML)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "created app %s/%s with %s version %s from catalog %s\n", r.flag.AppCRNamespace, crName, name, version, r.flag.Catalog)
+
+	if !r.flag.Wait {
+		return nil
+	}
+
+	var ctrlClient client.WithWatch
+	{
+		c := client.Options{
+			Scheme: k8sClients.Scheme(),
+		}
+		ctrlClient, err = client.NewWithWatch(k8sClients.RESTConfig(), c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var waiter *appwait.Waiter
+	{
+		c := appwait.Config{
+			Client: ctrlClient,
+			Logger: r.logger,
+			Stdout: r.stdout,
+
+			ChartNamespace: key.Namespace(r.cluster.Instance),
+		}
+		waiter, err = appwait.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.flag.Timeout)
+	defer cancel()
+
+	condition := appwait.Condition{
+		Status:  appwait.DeployedStatus,
+		Version: version,
+	}
+	err = waiter.Wait(ctx, r.flag.AppCRNamespace, crName, condition)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "app %s/%s is %s\n", r.flag.AppCRNamespace, crName, condition)
+
+	return nil
+}
+
+// latestVersion returns the highest version of the app listed in the
+// AppCatalogEntries of the catalog. This works for helm and oci catalogs
+// alike since app-operator creates the entries for both.
+func (r *runner) latestVersion(ctx context.Context, k8sClients k8sclient.Interface, name string) (string, error) {
+	var entries v1alpha1.AppCatalogEntryList
+	err := k8sClients.CtrlClient().List(ctx, &entries,
+		client.InNamespace(key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced)),
+		client.MatchingLabels{label.CatalogName: r.flag.Catalog},
+	)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var latest *semver.Version
+	for _, entry := range entries.Items {
+		if entry.Spec.AppName != name {
+			continue
+		}
+
+		v, err := semver.NewVersion(entry.Spec.Version)
+		if err != nil {
+			r.logger.Debugf(ctx, "ignoring appcatalogentry %#q with invalid version %#q", entry.Name, entry.Spec.Version)
+			continue
+		}
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+
+	if latest == nil {
+		return "", microerror.Maskf(notFoundError, "no appcatalogentry found for app %#q in catalog %#q, use --%s", name, r.flag.Catalog, version)
+	}
+
+	r.logger.Debugf(ctx, "resolved latest version %#q of app %#q", latest.Original(), name)
+
+	return latest.Original(), nil
+}
+
+// createApp creates the App CR processed by the unique app-operator. The user
+// values ConfigMap and Secret are written first and the App CR references
+// them from the start, so app-operator never installs the release without
+// them. The apptest library cannot be used since it neither references
+// Secrets nor takes labels, names the ConfigMap after the app rather than
+// the App CR and creates missing catalogs in the default namespace only.
+func (r *runner) createApp(ctx context.Context, k8sClients k8sclient.Interface, crName, name, version, valuesYAML, secretValuesYAML string) error {
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      crName,
+			Namespace: r.flag.AppCRNamespace,
+			Labels: map[string]string{
+				label.AppKubernetesName:  name,
+				label.AppOperatorVersion: key.UniqueAppOperatorVersion,
+			},
+		},
+		Spec: v1alpha1.AppSpec{
+			Catalog:          r.flag.Catalog,
+			CatalogNamespace: key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced),
+			KubeConfig: v1alpha1.AppSpecKubeConfig{
+				InCluster: true,
+			},
+			Name:      name,
+			Namespace: r.flag.Namespace,
+			Version:   version,
+		},
+	}
+
+	// App CRs of workload clusters live in the cluster namespace of the
+	// management cluster, like on real installations.
+	if r.flag.WorkloadCluster != "" {
+		kubeConfig, err := r.workloadKubeConfig(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		app.Labels[label.Cluster] = r.flag.WorkloadCluster
+		app.Spec.KubeConfig = kubeConfig
+	}
+
+	if valuesYAML != "" {
+		err := r.ensureUserValues(ctx, k8sClients, key.UserValuesName(crName), valuesYAML)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		app.Spec.UserConfig.ConfigMap.Name = key.UserValuesName(crName)
+		app.Spec.UserConfig.ConfigMap.Namespace = r.flag.AppCRNamespace
+	}
+
+	if secretValuesYAML != "" {
+		err := r.ensureUserSecret(ctx, k8sClients, key.UserSecretName(crName), secretValuesYAML)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		app.Spec.UserConfig.Secret.Name = key.UserSecretName(crName)
+		app.Spec.UserConfig.Secret.Namespace = r.flag.AppCRNamespace
+	}
+
+	metadata.Set(app, key.Labels(r.cluster.Instance))
+
+	r.logger.Debugf(ctx, "creating %#q app cr", crName)
+
+	err := k8sClients.CtrlClient().Create(ctx, app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "created %#q app cr", crName)
+
+	return nil
+}
+
+func (r *runner) ensureUserSecret(ctx context.Context, k8sClients k8sclient.Interface, name, valuesYAML string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.flag.AppCRNamespace,
+		},
+		Data: map[string][]byte{
+			"values": []byte(valuesYAML),
+		},
+	}
+	metadata.Set(secret, key.Labels(r.cluster.Instance))
+
+	r.logger.Debugf(ctx, "creating secret '%s/%s'", secret.Namespace, secret.Name)
+
+	secrets := k8sClients.K8sClient().CoreV1().Secrets(secret.Namespace)
+
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "updated existing secret '%s/%s'", secret.Namespace, secret.Name)
+
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "created secret '%s/%s'", secret.Namespace, secret.Name)
+
+	return nil
+}
+
+// readValues reads the values file at path and makes sure it is valid YAML
+// before anything is created in the cluster.
+func readValues(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	bytes, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var v map[string]interface{}
+	err = yaml.Unmarshal(bytes, &v)
+	if err != nil {
+		return "", microerror.Maskf(invalidFlagError, "%#q is not valid YAML: %s", path, err.Error())
+	}
+
+	return string(bytes), nil
+}
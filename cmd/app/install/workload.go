@@ -0,0 +1,71 @@
+package install
+
+import (
+	"context"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/metadata"
+)
+
+// workloadKubeConfig returns the kubeconfig reference of App CRs deploying
+// into the workload cluster set up by bootstrap --workload-kubeconfig.
+func (r *runner) workloadKubeConfig(ctx context.Context, k8sClients k8sclient.Interface) (v1alpha1.AppSpecKubeConfig, error) {
+	clusterNamespace := key.WorkloadClusterNamespace(r.flag.WorkloadCluster)
+	kubeConfigSecretName := key.WorkloadClusterKubeConfigSecretName(r.flag.WorkloadCluster)
+
+	_, err := k8sClients.K8sClient().CoreV1().Secrets(clusterNamespace).Get(ctx, kubeConfigSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return v1alpha1.AppSpecKubeConfig{}, microerror.Maskf(notFoundError, "kubeconfig secret '%s/%s' not found, run bootstrap with --workload-kubeconfig first", clusterNamespace, kubeConfigSecretName)
+	} else if err != nil {
+		return v1alpha1.AppSpecKubeConfig{}, microerror.Mask(err)
+	}
+
+	kubeConfig := v1alpha1.AppSpecKubeConfig{
+		Context: v1alpha1.AppSpecKubeConfigContext{
+			Name: r.flag.WorkloadCluster,
+		},
+		InCluster: false,
+		Secret: v1alpha1.AppSpecKubeConfigSecret{
+			Name:      kubeConfigSecretName,
+			Namespace: clusterNamespace,
+		},
+	}
+
+	return kubeConfig, nil
+}
+
+func (r *runner) ensureUserValues(ctx context.Context, k8sClients k8sclient.Interface, name, valuesYAML string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.flag.AppCRNamespace,
+		},
+		Data: map[string]string{
+			"values": valuesYAML,
+		},
+	}
+	metadata.Set(configMap, key.Labels(r.cluster.Instance))
+
+	configMaps := k8sClients.K8sClient().CoreV1().ConfigMaps(configMap.Namespace)
+
+	_, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "ensured configmap '%s/%s'", configMap.Namespace, configMap.Name)
+
+	return nil
+}
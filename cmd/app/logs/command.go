@@ -0,0 +1,66 @@
+package logs
+
+import (
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+)
+
+const (
+	name        = "logs <name>"
+	description = "Streams the logs of the workload pods deployed for an App CR."
+)
+
+type Config struct {
+	Cluster *cluster.Flag
+	Logger  micrologger.Logger
+	Stderr  io.Writer
+	Stdin   io.Reader
+	Stdout  io.Writer
+}
+
+func New(config Config) (*cobra.Command, error) {
+	if config.Cluster == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Cluster must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Stderr == nil {
+		config.Stderr = os.Stderr
+	}
+	if config.Stdin == nil {
+		config.Stdin = os.Stdin
+	}
+	if config.Stdout == nil {
+		config.Stdout = os.Stdout
+	}
+
+	f := &flag{}
+
+	r := &runner{
+		cluster: config.Cluster,
+		flag:    f,
+		logger:  config.Logger,
+		stderr:  config.Stderr,
+		stdin:   config.Stdin,
+		stdout:  config.Stdout,
+	}
+
+	c := &cobra.Command{
+		Use:   name,
+		Short: description,
+		Long:  description,
+		Args:  cobra.ExactArgs(1),
+		RunE:  r.Run,
+	}
+
+	f.Init(c)
+
+	return c, nil
+}
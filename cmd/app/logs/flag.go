@@ -0,0 +1,63 @@
+package logs
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	appCRNamespace = "app-cr-namespace"
+	follow         = "follow"
+	logLevel       = "log-level"
+	operators      = "operators"
+	since          = "since"
+	tail           = "tail"
+)
+
+type flag struct {
+	AppCRNamespace string
+	Follow         bool
+	LogLevel       string
+	Operators      bool
+	Since          time.Duration
+	Tail           int64
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppCRNamespace, appCRNamespace, metav1.NamespaceDefault, "Namespace of the App CR")
+	cmd.Flags().BoolVarP(&f.Follow, follow, "f", false, "Follow the logs until interrupted")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().BoolVar(&f.Operators, operators, false, "Include app-operator and chart-operator log lines mentioning the App")
+	cmd.Flags().DurationVar(&f.Since, since, 0, "Only show logs newer than this duration, e.g. 10m")
+	cmd.Flags().Int64Var(&f.Tail, tail, -1, "Number of recent lines shown per container, -1 shows all lines")
+}
+
+func (f *flag) Validate() error {
+	if f.AppCRNamespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", appCRNamespace)
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if f.Since < 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must not be negative", since)
+	}
+	if f.Tail < -1 {
+		return microerror.Maskf(invalidFlagError, "--%s must be -1 or greater", tail)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,271 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+// stream is a single container whose logs are printed. Lines are only
+// printed when they contain filter, unless it is empty.
+type stream struct {
+	Pod       corev1.Pod
+	Container string
+	Filter    string
+}
+
+func (s stream) prefix() string {
+	return fmt.Sprintf("[%s/%s]", s.Pod.Name, s.Container)
+}
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	r.cluster.DefaultAppCRNamespace(cmd, appCRNamespace, &r.flag.AppCRNamespace)
+
+	name := args[0]
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var app v1alpha1.App
+	err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.flag.AppCRNamespace, Name: name}, &app)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(notFoundError, "app CR '%s/%s' not found", r.flag.AppCRNamespace, name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// chart-operator names the release after the Chart CR and deploys it to
+	// the Chart CR's namespace.
+	releaseName := app.Name
+	releaseNamespace := app.Spec.Namespace
+	{
+		var chart v1alpha1.Chart
+		err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: key.Namespace(r.cluster.Instance), Name: app.Name}, &chart)
+		if apierrors.IsNotFound(err) {
+			r.logger.Debugf(ctx, "chart CR '%s/%s' not found", key.Namespace(r.cluster.Instance), app.Name)
+		} else if err != nil {
+			return microerror.Mask(err)
+		} else {
+			releaseName = chart.Spec.Name
+			releaseNamespace = chart.Spec.Namespace
+		}
+	}
+
+	streams, err := r.releaseStreams(ctx, k8sClients, releaseName, releaseNamespace, "")
+	if workload.IsReleaseNotFound(err) {
+		_, _ = fmt.Fprintf(r.stderr, "release %s/%s not found, app-operator and chart-operator did not deploy the app yet\n", releaseNamespace, releaseName)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if r.flag.Operators {
+		for _, operator := range []string{key.AppOperatorName(r.cluster.Instance), key.ChartOperatorName(r.cluster.Instance)} {
+			s, err := r.releaseStreams(ctx, k8sClients, operator, key.Namespace(r.cluster.Instance), app.Name)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			streams = append(streams, s...)
+		}
+	}
+
+	if len(streams) == 0 {
+		return microerror.Maskf(notFoundError, "no pods found for app CR '%s/%s'", app.Namespace, app.Name)
+	}
+
+	err = r.streamLogs(ctx, k8sClients, streams)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// releaseStreams returns a stream per container of the pods belonging to the
+// workloads of the Helm release.
+func (r *runner) releaseStreams(ctx context.Context, k8sClients k8sclient.Interface, name, namespace, filter string) ([]stream, error) {
+	workloads, err := workload.FromRelease(ctx, k8sClients.K8sClient(), name, namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var streams []stream
+	for _, w := range workloads {
+		r.logger.Debugf(ctx, "finding pods of %s", w)
+
+		pods, err := workload.Pods(ctx, k8sClients.K8sClient(), w)
+		if apierrors.IsNotFound(err) {
+			r.logger.Debugf(ctx, "%s not found", w)
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, pod := range pods {
+			for _, container := range pod.Spec.Containers {
+				streams = append(streams, stream{Pod: pod, Container: container.Name, Filter: filter})
+			}
+		}
+	}
+
+	return streams, nil
+}
+
+// streamLogs prints the logs of all streams concurrently, prefixing every
+// line with the pod and container. Streams ending early, e.g. because the
+// container is not running yet, are reported on stderr without aborting the
+// others.
+func (r *runner) streamLogs(ctx context.Context, k8sClients k8sclient.Interface, streams []stream) error {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, s := range streams {
+		wg.Add(1)
+		go func(s stream) {
+			defer wg.Done()
+
+			err := r.streamLog(ctx, k8sClients, s, &mutex)
+			if err != nil && ctx.Err() == nil {
+				mutex.Lock()
+				_, _ = fmt.Fprintf(r.stderr, "%s %s\n", s.prefix(), err)
+				mutex.Unlock()
+			}
+		}(s)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (r *runner) streamLog(ctx context.Context, k8sClients k8sclient.Interface, s stream, mutex *sync.Mutex) error {
+	opts := &corev1.PodLogOptions{
+		Container: s.Container,
+		Follow:    r.flag.Follow,
+	}
+	if r.flag.Tail >= 0 {
+		opts.TailLines = &r.flag.Tail
+	}
+	if r.flag.Since > 0 {
+		seconds := int64(r.flag.Since.Seconds())
+		opts.SinceSeconds = &seconds
+	}
+
+	rc, err := k8sClients.K8sClient().CoreV1().Pods(s.Pod.Namespace).GetLogs(s.Pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if s.Filter != "" && !strings.Contains(line, s.Filter) {
+			continue
+		}
+
+		mutex.Lock()
+		_, _ = fmt.Fprintf(r.stdout, "%s %s\n", s.prefix(), line)
+		mutex.Unlock()
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
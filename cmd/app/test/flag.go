@@ -0,0 +1,57 @@
+package test
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	appCRNamespace = "app-cr-namespace"
+	logLevel       = "log-level"
+	logs           = "logs"
+	output         = "output"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+type flag struct {
+	AppCRNamespace string
+	LogLevel       string
+	Logs           bool
+	Output         string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppCRNamespace, appCRNamespace, metav1.NamespaceDefault, "Namespace of the App CR")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().BoolVar(&f.Logs, logs, true, "Collect the logs of the test hook pods")
+	cmd.Flags().StringVarP(&f.Output, output, "o", outputTable, "Output format of the results. Either table or json.")
+}
+
+func (f *flag) Validate() error {
+	if f.AppCRNamespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", appCRNamespace)
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if !containsString([]string{outputJSON, outputTable}, f.Output) {
+		return microerror.Maskf(invalidFlagError, "--%s must be either %s or %s", output, outputTable, outputJSON)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
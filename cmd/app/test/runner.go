@@ -0,0 +1,436 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"oras.land/oras-go/pkg/content"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+// hookResult is the outcome of a single test hook.
+type hookResult struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace"`
+	Phase       string `json:"phase"`
+	StartedAt   string `json:"startedAt,omitempty"`
+	CompletedAt string `json:"completedAt,omitempty"`
+	Duration    string `json:"duration,omitempty"`
+	Logs        string `json:"logs,omitempty"`
+}
+
+type result struct {
+	App       string       `json:"app"`
+	Release   string       `json:"release"`
+	Namespace string       `json:"namespace"`
+	Passed    bool         `json:"passed"`
+	Error     string       `json:"error,omitempty"`
+	Hooks     []hookResult `json:"hooks"`
+}
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	r.cluster.DefaultAppCRNamespace(cmd, appCRNamespace, &r.flag.AppCRNamespace)
+
+	name := args[0]
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var app v1alpha1.App
+	err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.flag.AppCRNamespace, Name: name}, &app)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(notFoundError, "app CR '%s/%s' not found", r.flag.AppCRNamespace, name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// App CRs of workload clusters have their Chart CR and release in the
+	// cluster their kubeconfig Secret points to, managed by the default
+	// chart-operator there.
+	releaseClients := k8sClients
+	chartNamespace := key.Namespace(r.cluster.Instance)
+	if !app.Spec.KubeConfig.InCluster {
+		releaseClients, err = r.workloadClients(ctx, k8sClients, app)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		chartNamespace = key.Namespace("")
+	}
+
+	var helmClient helmclient.Interface
+	{
+		c := helmclient.Config{
+			K8sClient:       releaseClients.K8sClient(),
+			Logger:          r.logger,
+			RegistryOptions: &content.RegistryOptions{},
+			RestClient:      releaseClients.RESTClient(),
+			RestConfig:      releaseClients.RESTConfig(),
+		}
+		helmClient, err = helmclient.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// chart-operator names the release after the Chart CR and deploys it to
+	// the Chart CR's namespace.
+	var chart v1alpha1.Chart
+	err = releaseClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: chartNamespace, Name: app.Name}, &chart)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(notFoundError, "chart CR '%s/%s' not found, app-operator did not process the app yet", chartNamespace, app.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	releaseName := chart.Spec.Name
+	releaseNamespace := chart.Spec.Namespace
+
+	res := result{
+		App:       fmt.Sprintf("%s/%s", app.Namespace, app.Name),
+		Release:   releaseName,
+		Namespace: releaseNamespace,
+		Passed:    true,
+	}
+
+	_, _ = fmt.Fprintf(r.stderr, "running tests of release %s/%s\n", releaseNamespace, releaseName)
+
+	err = helmClient.RunReleaseTest(ctx, releaseNamespace, releaseName)
+	if helmclient.IsTestReleaseFailure(err) {
+		res.Passed = false
+	} else if helmclient.IsReleaseNotFound(err) {
+		return microerror.Maskf(notFoundError, "release '%s/%s' not found", releaseNamespace, releaseName)
+	} else if err != nil {
+		// Errors like timeouts still leave the hook phases in the
+		// release, so the results are collected anyway.
+		res.Passed = false
+		res.Error = err.Error()
+	}
+
+	// Helm records the last run of every hook in the release, which is
+	// where the per hook results are read from.
+	res.Hooks, err = r.hookResults(ctx, releaseClients, releaseName, releaseNamespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, h := range res.Hooks {
+		if h.Phase != string(release.HookPhaseSucceeded) {
+			res.Passed = false
+		}
+	}
+
+	err = r.print(res)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if !res.Passed {
+		return microerror.Maskf(testFailedError, "tests of release '%s/%s' failed", releaseNamespace, releaseName)
+	}
+
+	return nil
+}
+
+// workloadClients returns the clients of the workload cluster the App CR
+// deploys into, built from its kubeconfig Secret in the management cluster.
+func (r *runner) workloadClients(ctx context.Context, k8sClients k8sclient.Interface, app v1alpha1.App) (k8sclient.Interface, error) {
+	secret := app.Spec.KubeConfig.Secret
+
+	workloadCluster, err := cluster.FromKubeConfigSecret(ctx, k8sClients.K8sClient(), secret.Namespace, secret.Name, app.Spec.KubeConfig.Context.Name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var workloadClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: workloadCluster.RESTConfig(),
+		}
+		workloadClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return workloadClients, nil
+}
+
+func (r *runner) hookResults(ctx context.Context, k8sClients k8sclient.Interface, name, namespace string) ([]hookResult, error) {
+	secrets := driver.NewSecrets(k8sClients.K8sClient().CoreV1().Secrets(namespace))
+	releases, err := secrets.Query(map[string]string{"name": name, "owner": "helm"})
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, microerror.Maskf(notFoundError, "release '%s/%s' not found", namespace, name)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	latest := releases[0]
+	for _, rel := range releases {
+		if rel.Version > latest.Version {
+			latest = rel
+		}
+	}
+
+	var results []hookResult
+	for _, hook := range latest.Hooks {
+		if !isTestHook(hook) {
+			continue
+		}
+
+		h := hookResult{
+			Name:      hook.Name,
+			Kind:      hook.Kind,
+			Namespace: hookNamespace(hook, namespace),
+			Phase:     string(hook.LastRun.Phase),
+		}
+		if h.Phase == "" {
+			h.Phase = "NotRun"
+		}
+		if !hook.LastRun.StartedAt.IsZero() {
+			h.StartedAt = hook.LastRun.StartedAt.Format(time.RFC3339)
+		}
+		if !hook.LastRun.CompletedAt.IsZero() {
+			h.CompletedAt = hook.LastRun.CompletedAt.Format(time.RFC3339)
+			if !hook.LastRun.StartedAt.IsZero() {
+				h.Duration = hook.LastRun.CompletedAt.Sub(hook.LastRun.StartedAt).Round(time.Second).String()
+			}
+		}
+
+		if r.flag.Logs {
+			h.Logs, err = r.hookLogs(ctx, k8sClients, h)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		results = append(results, h)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return results, nil
+}
+
+// hookLogs returns the logs of all containers of the hook's pods. Pods of
+// Job hooks are found via the job-name label. Hooks removed by their delete
+// policy have no logs left.
+func (r *runner) hookLogs(ctx context.Context, k8sClients k8sclient.Interface, h hookResult) (string, error) {
+	var pods []corev1.Pod
+	switch h.Kind {
+	case "Pod":
+		pod, err := k8sClients.K8sClient().CoreV1().Pods(h.Namespace).Get(ctx, h.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			r.logger.Debugf(ctx, "pod '%s/%s' of test hook was deleted", h.Namespace, h.Name)
+			return "", nil
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+		pods = append(pods, *pod)
+	case "Job":
+		list, err := k8sClients.K8sClient().CoreV1().Pods(h.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("job-name=%s", h.Name),
+		})
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		pods = list.Items
+	default:
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			b, err := k8sClients.K8sClient().CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: container.Name}).DoRaw(ctx)
+			if err != nil {
+				r.logger.Debugf(ctx, "getting logs of '%s/%s' container %#q failed: %s", pod.Namespace, pod.Name, container.Name, err)
+				continue
+			}
+
+			for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+				if line == "" {
+					continue
+				}
+				_, _ = fmt.Fprintf(&buf, "[%s/%s] %s\n", pod.Name, container.Name, line)
+			}
+		}
+	}
+
+	return buf.String(), nil
+}
+
+func (r *runner) print(res result) error {
+	if r.flag.Output == outputJSON {
+		if res.Hooks == nil {
+			res.Hooks = []hookResult{}
+		}
+
+		e := json.NewEncoder(r.stdout)
+		e.SetIndent("", "  ")
+		err := e.Encode(res)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(r.stdout, 0, 8, 2, ' ', 0)
+
+	if len(res.Hooks) == 0 {
+		_, _ = fmt.Fprintf(w, "release %s/%s has no test hooks\n", res.Namespace, res.Release)
+	} else {
+		_, _ = fmt.Fprintln(w, "HOOK\tKIND\tPHASE\tDURATION")
+		for _, h := range res.Hooks {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", h.Name, h.Kind, h.Phase, h.Duration)
+		}
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, h := range res.Hooks {
+		if h.Logs == "" {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(r.stdout, "\nLogs of %s %s:\n%s", h.Kind, h.Name, h.Logs)
+	}
+
+	if res.Error != "" {
+		_, _ = fmt.Fprintf(r.stdout, "\nerror: %s\n", res.Error)
+	}
+
+	return nil
+}
+
+func isTestHook(hook *release.Hook) bool {
+	for _, e := range hook.Events {
+		if e == release.HookTest {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hookNamespace returns the namespace set in the hook manifest, which
+// defaults to the release namespace.
+func hookNamespace(hook *release.Hook, namespace string) string {
+	var head struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+
+	err := yaml.Unmarshal([]byte(hook.Manifest), &head)
+	if err != nil || head.Metadata.Namespace == "" {
+		return namespace
+	}
+
+	return head.Metadata.Namespace
+}
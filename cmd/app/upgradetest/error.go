@@ -0,0 +1,43 @@
+package upgradetest
+
+import "github.com/giantswarm/microerror"
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidFlagError = &microerror.Error{
+	Kind: "invalidFlagError",
+}
+
+// IsInvalidFlag asserts invalidFlagError.
+func IsInvalidFlag(err error) bool {
+	return microerror.Cause(err) == invalidFlagError
+}
+
+var alreadyExistsError = &microerror.Error{
+	Kind: "alreadyExistsError",
+}
+
+// IsAlreadyExists asserts alreadyExistsError.
+func IsAlreadyExists(err error) bool {
+	return microerror.Cause(err) == alreadyExistsError
+}
+
+var upgradeTestFailedError = &microerror.Error{
+	Kind: "upgradeTestFailedError",
+}
+
+// IsUpgradeTestFailed asserts upgradeTestFailedError.
+func IsUpgradeTestFailed(err error) bool {
+	return microerror.Cause(err) == upgradeTestFailedError
+}
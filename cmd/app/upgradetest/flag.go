@@ -0,0 +1,98 @@
+package upgradetest
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	appCRNamespace = "app-cr-namespace"
+	catalog        = "catalog"
+	catalogURL     = "catalog-url"
+	cleanup        = "cleanup"
+	from           = "from"
+	logLevel       = "log-level"
+	namespace      = "namespace"
+	output         = "output"
+	timeout        = "timeout"
+	to             = "to"
+	toValues       = "to-values"
+	values         = "values"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+type flag struct {
+	AppCRNamespace string
+	Catalog        string
+	CatalogURL     string
+	Cleanup        bool
+	From           string
+	LogLevel       string
+	Namespace      string
+	Output         string
+	Timeout        time.Duration
+	To             string
+	ToValues       string
+	Values         string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppCRNamespace, appCRNamespace, metav1.NamespaceDefault, "Namespace of the App CR and its user values")
+	cmd.Flags().StringVarP(&f.Catalog, catalog, "c", "", "Catalog the app is installed from, defaults to the chartmuseum catalog created by bootstrap")
+	cmd.Flags().StringVar(&f.CatalogURL, catalogURL, "", "Helm repository URL used to create the catalog when it does not exist yet")
+	cmd.Flags().BoolVar(&f.Cleanup, cleanup, false, "Delete the App CR and its user values after the test")
+	cmd.Flags().StringVar(&f.From, from, "", "Version installed first")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().StringVarP(&f.Namespace, namespace, "n", metav1.NamespaceDefault, "Namespace the app is deployed to")
+	cmd.Flags().StringVarP(&f.Output, output, "o", outputTable, "Output format of the report. Either table or json.")
+	cmd.Flags().DurationVar(&f.Timeout, timeout, 10*time.Minute, "Maximum time to wait for each deployment")
+	cmd.Flags().StringVar(&f.To, to, "", "Version the app is upgraded to")
+	cmd.Flags().StringVar(&f.ToValues, toValues, "", "Path to a YAML file with user values replacing --values during the upgrade")
+	cmd.Flags().StringVarP(&f.Values, values, "f", "", "Path to a YAML file with user values for the installed version")
+}
+
+func (f *flag) Validate() error {
+	if f.AppCRNamespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", appCRNamespace)
+	}
+	if f.From == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", from)
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", namespace)
+	}
+	if !containsString([]string{outputJSON, outputTable}, f.Output) {
+		return microerror.Maskf(invalidFlagError, "--%s must be either %s or %s", output, outputTable, outputJSON)
+	}
+	if f.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be positive", timeout)
+	}
+	if f.To == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", to)
+	}
+	if f.From == f.To {
+		return microerror.Maskf(invalidFlagError, "--%s and --%s must differ", from, to)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package upgradetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+const (
+	monitorInterval = 2 * time.Second
+)
+
+// availability tracks how a single workload behaved during the upgrade.
+type availability struct {
+	MinAvailable int32
+	Unavailable  time.Duration
+	Removed      bool
+
+	// since is when the workload became unavailable, zero while it is
+	// available.
+	since time.Time
+}
+
+// monitor samples the availability of the workloads deployed by the old
+// version while the upgrade is rolled out. A workload counts as unavailable
+// when it wants pods but none of them are available.
+type monitor struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	mutex     sync.Mutex
+	workloads []workload.Workload
+	stats     map[workload.Workload]*availability
+}
+
+func newMonitor(k8sClient kubernetes.Interface, logger micrologger.Logger, workloads []workload.Workload) *monitor {
+	m := &monitor{
+		k8sClient: k8sClient,
+		logger:    logger,
+
+		stats: map[workload.Workload]*availability{},
+	}
+
+	for _, w := range workloads {
+		// Jobs are expected to finish, so having no active pods is not an
+		// outage.
+		if w.Kind == workload.KindJob {
+			continue
+		}
+
+		m.workloads = append(m.workloads, w)
+		m.stats[w] = &availability{MinAvailable: -1}
+	}
+
+	return m
+}
+
+// run samples the workloads until ctx is done.
+func (m *monitor) run(ctx context.Context) {
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+
+	for {
+		m.sample(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *monitor) sample(ctx context.Context) {
+	now := time.Now()
+
+	for _, w := range m.workloads {
+		available, desired, err := workload.Availability(ctx, m.k8sClient, w)
+
+		m.mutex.Lock()
+
+		s := m.stats[w]
+		switch {
+		case apierrors.IsNotFound(err):
+			s.Removed = true
+		case err != nil:
+			if ctx.Err() == nil {
+				m.logger.Debugf(ctx, "sampling %s failed: %s", w, err)
+			}
+		default:
+			s.Removed = false
+
+			if s.MinAvailable < 0 || available < s.MinAvailable {
+				s.MinAvailable = available
+			}
+
+			if desired > 0 && available == 0 {
+				if s.since.IsZero() {
+					m.logger.Debugf(ctx, "%s became unavailable", w)
+					s.since = now
+				}
+			} else if !s.since.IsZero() {
+				m.logger.Debugf(ctx, "%s became available again", w)
+				s.Unavailable += now.Sub(s.since)
+				s.since = time.Time{}
+			}
+		}
+
+		m.mutex.Unlock()
+	}
+}
+
+func (m *monitor) results() []workloadResult {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+
+	var results []workloadResult
+	for _, w := range m.workloads {
+		s := m.stats[w]
+
+		unavailable := s.Unavailable
+		if !s.since.IsZero() {
+			unavailable += now.Sub(s.since)
+		}
+
+		minAvailable := s.MinAvailable
+		if minAvailable < 0 {
+			minAvailable = 0
+		}
+
+		results = append(results, workloadResult{
+			Workload:     w.String(),
+			Passed:       unavailable == 0,
+			MinAvailable: minAvailable,
+			Unavailable:  unavailable.Round(100 * time.Millisecond).String(),
+			Removed:      s.Removed,
+		})
+	}
+
+	return results
+}
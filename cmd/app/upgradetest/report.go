@@ -0,0 +1,84 @@
+package upgradetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/giantswarm/microerror"
+)
+
+type report struct {
+	App       string           `json:"app"`
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	Passed    bool             `json:"passed"`
+	Steps     []stepResult     `json:"steps"`
+	Workloads []workloadResult `json:"workloads"`
+}
+
+type stepResult struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Duration string `json:"duration"`
+	Message  string `json:"message,omitempty"`
+}
+
+type workloadResult struct {
+	Workload     string `json:"workload"`
+	Passed       bool   `json:"passed"`
+	MinAvailable int32  `json:"minAvailable"`
+	Unavailable  string `json:"unavailable"`
+	Removed      bool   `json:"removed,omitempty"`
+}
+
+func (r report) print(w io.Writer, format string) error {
+	if format == outputJSON {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		err := e.Encode(r)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "STEP\tRESULT\tDURATION\tMESSAGE")
+	for _, s := range r.Steps {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Name, result(s.Passed), s.Duration, s.Message)
+	}
+	_, _ = fmt.Fprintln(tw)
+
+	if len(r.Workloads) > 0 {
+		_, _ = fmt.Fprintln(tw, "WORKLOAD\tRESULT\tMIN AVAILABLE\tUNAVAILABLE")
+		for _, wl := range r.Workloads {
+			unavailable := wl.Unavailable
+			if wl.Removed {
+				unavailable = fmt.Sprintf("%s (removed by upgrade)", unavailable)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", wl.Workload, result(wl.Passed), wl.MinAvailable, unavailable)
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+
+	_, _ = fmt.Fprintf(tw, "upgrade of %s from %s to %s: %s\n", r.App, r.From, r.To, result(r.Passed))
+
+	err := tw.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func result(passed bool) string {
+	if passed {
+		return "PASS"
+	}
+
+	return "FAIL"
+}
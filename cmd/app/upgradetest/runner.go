@@ -0,0 +1,479 @@
+package upgradetest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/apptest"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/metadata"
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	r.cluster.DefaultAppCRNamespace(cmd, appCRNamespace, &r.flag.AppCRNamespace)
+
+	if r.flag.Catalog == "" {
+		r.flag.Catalog = key.ChartMuseumCatalogName(r.cluster.Instance)
+	}
+
+	name := args[0]
+
+	valuesYAML, err := readValues(r.flag.Values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	toValuesYAML, err := readValues(r.flag.ToValues)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var appTest apptest.Interface
+	{
+		c := apptest.Config{
+			KubeConfig: targetCluster.KubeConfig(),
+			Logger:     r.logger,
+		}
+		appTest, err = apptest.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var ctrlClient client.WithWatch
+	{
+		c := client.Options{
+			Scheme: k8sClients.Scheme(),
+		}
+		ctrlClient, err = client.NewWithWatch(k8sClients.RESTConfig(), c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var waiter *appwait.Waiter
+	{
+		c := appwait.Config{
+			Client: ctrlClient,
+			Logger: r.logger,
+			Stdout: r.stderr,
+
+			ChartNamespace: key.Namespace(r.cluster.Instance),
+		}
+		waiter, err = appwait.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.flag.AppCRNamespace, Name: name}, &v1alpha1.App{})
+	if err == nil {
+		return microerror.Maskf(alreadyExistsError, "app CR '%s/%s' already exists, the upgrade test needs a fresh install", r.flag.AppCRNamespace, name)
+	} else if !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	catalogURL, err := r.catalogURL(ctx, k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	rep := report{
+		App:    name,
+		From:   r.flag.From,
+		To:     r.flag.To,
+		Passed: true,
+	}
+
+	// step runs f and records its result. Once a step failed all further
+	// steps are skipped.
+	step := func(stepName string, f func() error) bool {
+		if !rep.Passed {
+			rep.Steps = append(rep.Steps, stepResult{Name: stepName, Duration: "0s", Message: "skipped"})
+			return false
+		}
+
+		_, _ = fmt.Fprintf(r.stderr, "running step %s\n", stepName)
+
+		start := time.Now()
+		err := f()
+		s := stepResult{
+			Name:     stepName,
+			Passed:   err == nil,
+			Duration: time.Since(start).Round(time.Second).String(),
+		}
+		if err != nil {
+			s.Message = microerror.Pretty(err, false)
+			rep.Passed = false
+		}
+
+		rep.Steps = append(rep.Steps, s)
+
+		return err == nil
+	}
+
+	step("install", func() error {
+		apps := []apptest.App{
+			{
+				AppCRName:      name,
+				AppCRNamespace: r.flag.AppCRNamespace,
+				CatalogName:    r.flag.Catalog,
+				CatalogURL:     catalogURL,
+				Name:           name,
+				Namespace:      r.flag.Namespace,
+				ValuesYAML:     valuesYAML,
+				Version:        r.flag.From,
+			},
+		}
+		err := appTest.InstallApps(ctx, apps)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = metadata.PatchApp(ctx, k8sClients.CtrlClient(), r.flag.AppCRNamespace, name, key.Labels(r.cluster.Instance))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+
+	step(fmt.Sprintf("deployed %s", r.flag.From), func() error {
+		return r.wait(ctx, waiter, name, r.flag.From)
+	})
+
+	var m *monitor
+	step("discover workloads", func() error {
+		workloads, err := r.workloads(ctx, k8sClients, name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		m = newMonitor(k8sClients.K8sClient(), r.logger, workloads)
+
+		return nil
+	})
+
+	monitorCtx, stopMonitor := context.WithCancel(ctx)
+	defer stopMonitor()
+
+	var wg sync.WaitGroup
+	if m != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.run(monitorCtx)
+		}()
+	}
+
+	step("upgrade", func() error {
+		return r.upgrade(ctx, k8sClients, name, toValuesYAML)
+	})
+
+	step(fmt.Sprintf("deployed %s", r.flag.To), func() error {
+		return r.wait(ctx, waiter, name, r.flag.To)
+	})
+
+	stopMonitor()
+	wg.Wait()
+
+	if m != nil {
+		m.sample(ctx)
+		rep.Workloads = m.results()
+
+		step("availability", func() error {
+			for _, w := range rep.Workloads {
+				if !w.Passed {
+					return microerror.Maskf(executionFailedError, "%s had no available pods for %s", w.Workload, w.Unavailable)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if r.flag.Cleanup {
+		err = r.cleanup(ctx, k8sClients, waiter, name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = rep.print(r.stdout, r.flag.Output)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if !rep.Passed {
+		return microerror.Maskf(upgradeTestFailedError, "upgrade of %#q from %#q to %#q failed", name, r.flag.From, r.flag.To)
+	}
+
+	return nil
+}
+
+// catalogURL returns the URL passed via --catalog-url or the storage URL of
+// the existing Catalog CR. An empty URL lets the apptest library resolve the
+// well known Giant Swarm catalogs.
+func (r *runner) catalogURL(ctx context.Context, k8sClients k8sclient.Interface) (string, error) {
+	if r.flag.CatalogURL != "" {
+		return r.flag.CatalogURL, nil
+	}
+
+	var cr v1alpha1.Catalog
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced), Name: r.flag.Catalog}, &cr)
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "catalog %#q not found", r.flag.Catalog)
+		return "", nil
+	} else if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return cr.Spec.Storage.URL, nil
+}
+
+func (r *runner) wait(ctx context.Context, waiter *appwait.Waiter, name, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.flag.Timeout)
+	defer cancel()
+
+	condition := appwait.Condition{
+		Status:  appwait.DeployedStatus,
+		Version: version,
+	}
+	err := waiter.Wait(ctx, r.flag.AppCRNamespace, name, condition)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// workloads returns the workloads of the release chart-operator created for
+// the App CR.
+func (r *runner) workloads(ctx context.Context, k8sClients k8sclient.Interface, name string) ([]workload.Workload, error) {
+	var chart v1alpha1.Chart
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: key.Namespace(r.cluster.Instance), Name: name}, &chart)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	workloads, err := workload.FromRelease(ctx, k8sClients.K8sClient(), chart.Spec.Name, chart.Spec.Namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, w := range workloads {
+		r.logger.Debugf(ctx, "monitoring %s", w)
+	}
+
+	return workloads, nil
+}
+
+// upgrade bumps the App CR version and, when --to-values is set, replaces
+// the user values the same way the apptest library created them.
+func (r *runner) upgrade(ctx context.Context, k8sClients k8sclient.Interface, name, toValuesYAML string) error {
+	var app v1alpha1.App
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.flag.AppCRNamespace, Name: name}, &app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	patch := client.MergeFrom(app.DeepCopy())
+
+	if toValuesYAML != "" {
+		configMapName := key.UserValuesName(app.Name)
+
+		err = r.ensureUserValues(ctx, k8sClients, configMapName, toValuesYAML)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		app.Spec.UserConfig.ConfigMap.Name = configMapName
+		app.Spec.UserConfig.ConfigMap.Namespace = r.flag.AppCRNamespace
+	}
+
+	app.Spec.Version = r.flag.To
+
+	err = k8sClients.CtrlClient().Patch(ctx, &app, patch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "updated '%s/%s' app cr to version %#q", app.Namespace, app.Name, r.flag.To)
+
+	return nil
+}
+
+func (r *runner) ensureUserValues(ctx context.Context, k8sClients k8sclient.Interface, name, valuesYAML string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.flag.AppCRNamespace,
+		},
+		Data: map[string]string{
+			"values": valuesYAML,
+		},
+	}
+
+	metadata.Set(configMap, key.Labels(r.cluster.Instance))
+
+	configMaps := k8sClients.K8sClient().CoreV1().ConfigMaps(configMap.Namespace)
+
+	_, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "ensured configmap '%s/%s'", configMap.Namespace, configMap.Name)
+
+	return nil
+}
+
+// cleanup deletes the App CR and its user values and waits for app-operator
+// to remove the Chart CR.
+func (r *runner) cleanup(ctx context.Context, k8sClients k8sclient.Interface, waiter *appwait.Waiter, name string) error {
+	_, _ = fmt.Fprintf(r.stderr, "deleting app %s/%s\n", r.flag.AppCRNamespace, name)
+
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.flag.AppCRNamespace,
+		},
+	}
+	err := k8sClients.CtrlClient().Delete(ctx, app)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	err = k8sClients.K8sClient().CoreV1().ConfigMaps(r.flag.AppCRNamespace).Delete(ctx, key.UserValuesName(name), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.flag.Timeout)
+	defer cancel()
+
+	err = waiter.Wait(ctx, r.flag.AppCRNamespace, name, appwait.Condition{Status: appwait.DeletedStatus})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// readValues reads the values file at path and makes sure it is valid YAML
+// before anything is created in the cluster.
+func readValues(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	bytes, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var v map[string]interface{}
+	err = yaml.Unmarshal(bytes, &v)
+	if err != nil {
+		return "", microerror.Maskf(invalidFlagError, "%#q is not valid YAML: %s", path, err.Error())
+	}
+
+	return string(bytes), nil
+}
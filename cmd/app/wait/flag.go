@@ -0,0 +1,60 @@
+package wait
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+)
+
+const (
+	appCRNamespace = "app-cr-namespace"
+	forCondition   = "for"
+	logLevel       = "log-level"
+	timeout        = "timeout"
+)
+
+type flag struct {
+	AppCRNamespace string
+	For            string
+	LogLevel       string
+	Timeout        time.Duration
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppCRNamespace, appCRNamespace, metav1.NamespaceDefault, "Namespace of the App CR")
+	cmd.Flags().StringVar(&f.For, forCondition, appwait.DeployedStatus, "Condition to wait for. Either deployed, deleted or version=X.")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().DurationVar(&f.Timeout, timeout, 10*time.Minute, "Maximum time to wait for the condition")
+}
+
+func (f *flag) Validate() error {
+	if f.AppCRNamespace == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", appCRNamespace)
+	}
+	_, err := appwait.ParseCondition(f.For)
+	if err != nil {
+		return microerror.Maskf(invalidFlagError, "--%s %s", forCondition, err.Error())
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if f.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be positive", timeout)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
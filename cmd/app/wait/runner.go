@@ -0,0 +1,142 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	r.cluster.DefaultAppCRNamespace(cmd, appCRNamespace, &r.flag.AppCRNamespace)
+
+	name := args[0]
+
+	condition, err := appwait.ParseCondition(r.flag.For)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var ctrlClient client.WithWatch
+	{
+		c := client.Options{
+			Scheme: k8sClients.Scheme(),
+		}
+		ctrlClient, err = client.NewWithWatch(k8sClients.RESTConfig(), c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var waiter *appwait.Waiter
+	{
+		c := appwait.Config{
+			Client: ctrlClient,
+			Logger: r.logger,
+			Stdout: r.stdout,
+
+			ChartNamespace: key.Namespace(r.cluster.Instance),
+		}
+		waiter, err = appwait.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.flag.Timeout)
+	defer cancel()
+
+	err = waiter.Wait(ctx, r.flag.AppCRNamespace, name, condition)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "app %s/%s is %s\n", r.flag.AppCRNamespace, name, condition)
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+const (
+	repositoryTypeHelm = "helm"
+	repositoryTypeOCI  = "oci"
+)
+
+// catalogsConfig is the format of the file passed via --catalogs-file, e.g.
+//
+//	catalogs:
+//	- name: giantswarm
+//	  visibility: public
+//	  repositories:
+//	  - type: oci
+//	    url: oci://gsoci.azurecr.io/charts/giantswarm/
+//	  - type: helm
+//	    url: https://giantswarm.github.io/giantswarm-catalog/
+//	  secret:
+//	    name: giantswarm-catalog-credentials
+//	    namespace: giantswarm
+type catalogsConfig struct {
+	Catalogs []catalog `json:"catalogs"`
+}
+
+type catalog struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type and Visibility are set as the catalog-type and
+	// catalog-visibility labels, e.g. stable and public.
+	Type       string `json:"type,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+	// Repositories are tried by app-operator in order, so additional
+	// entries act as mirrors of the first one.
+	Repositories []catalogRepository `json:"repositories"`
+	// ConfigMap and Secret reference existing catalog values, e.g.
+	// registry credentials for private catalogs.
+	ConfigMap *objectReference `json:"configMap,omitempty"`
+	Secret    *objectReference `json:"secret,omitempty"`
+}
+
+type catalogRepository struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type objectReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// readCatalogs reads and validates the catalogs declared in path. Catalogs
+// without a namespace default to the catalog namespace of the app platform
+// instance.
+func readCatalogs(path, instance string, namespaced bool) ([]catalog, error) {
+	bytes, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var f catalogsConfig
+	err = yaml.UnmarshalStrict(bytes, &f)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%#q: %s", path, err.Error())
+	}
+
+	seen := map[string]bool{}
+	for i, c := range f.Catalogs {
+		if c.Name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%#q: catalog %d must have a name", path, i)
+		}
+		if len(c.Repositories) == 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%#q: catalog %#q must have at least one repository", path, c.Name)
+		}
+
+		for _, repo := range c.Repositories {
+			switch repo.Type {
+			case repositoryTypeHelm:
+			case repositoryTypeOCI:
+				if !strings.HasPrefix(repo.URL, ociScheme) {
+					return nil, microerror.Maskf(invalidConfigError, "%#q: catalog %#q repository %#q must start with %#q", path, c.Name, repo.URL, ociScheme)
+				}
+			default:
+				return nil, microerror.Maskf(invalidConfigError, "%#q: catalog %#q repository type must be either %#q or %#q", path, c.Name, repositoryTypeHelm, repositoryTypeOCI)
+			}
+
+			if repo.URL == "" {
+				return nil, microerror.Maskf(invalidConfigError, "%#q: catalog %#q repository must have a url", path, c.Name)
+			}
+		}
+
+		for _, ref := range []*objectReference{c.ConfigMap, c.Secret} {
+			if ref != nil && (ref.Name == "" || ref.Namespace == "") {
+				return nil, microerror.Maskf(invalidConfigError, "%#q: catalog %#q config references must have a name and namespace", path, c.Name)
+			}
+		}
+
+		if c.Namespace == "" {
+			f.Catalogs[i].Namespace = key.CatalogNamespace(instance, namespaced)
+		}
+
+		// Later entries would silently overwrite earlier ones.
+		id := fmt.Sprintf("%s/%s", f.Catalogs[i].Namespace, c.Name)
+		if seen[id] {
+			return nil, microerror.Maskf(invalidConfigError, "%#q: catalog '%s' is declared more than once", path, id)
+		}
+		seen[id] = true
+		if c.Title == "" {
+			f.Catalogs[i].Title = c.Name
+		}
+		if c.Description == "" {
+			f.Catalogs[i].Description = c.Name
+		}
+	}
+
+	return f.Catalogs, nil
+}
@@ -0,0 +1,265 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_readCatalogs(t *testing.T) {
+	testCases := []struct {
+		name             string
+		catalogs         string
+		instance         string
+		namespaced       bool
+		expectedCatalogs []catalog
+		errorMatcher     func(error) bool
+	}{
+		{
+			name: "case 0: defaults and mirrors",
+			catalogs: `catalogs:
+- name: giantswarm
+  type: stable
+  visibility: public
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+  - type: oci
+    url: oci://gsoci.azurecr.io/charts/giantswarm/
+- name: private
+  namespace: giantswarm
+  title: Private
+  description: Private apps
+  repositories:
+  - type: oci
+    url: oci://registry.example.com/charts/
+  secret:
+    name: private-catalog
+    namespace: giantswarm
+`,
+			expectedCatalogs: []catalog{
+				{
+					Name:        "giantswarm",
+					Namespace:   "default",
+					Title:       "giantswarm",
+					Description: "giantswarm",
+					Type:        "stable",
+					Visibility:  "public",
+					Repositories: []catalogRepository{
+						{Type: "helm", URL: "https://giantswarm.github.io/giantswarm-catalog/"},
+						{Type: "oci", URL: "oci://gsoci.azurecr.io/charts/giantswarm/"},
+					},
+				},
+				{
+					Name:        "private",
+					Namespace:   "giantswarm",
+					Title:       "Private",
+					Description: "Private apps",
+					Repositories: []catalogRepository{
+						{Type: "oci", URL: "oci://registry.example.com/charts/"},
+					},
+					Secret: &objectReference{Name: "private-catalog", Namespace: "giantswarm"},
+				},
+			},
+		},
+		{
+			name: "case 1: same name in different namespaces",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+- name: giantswarm
+  namespace: giantswarm
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+`,
+			expectedCatalogs: []catalog{
+				{
+					Name:         "giantswarm",
+					Namespace:    "default",
+					Title:        "giantswarm",
+					Description:  "giantswarm",
+					Repositories: []catalogRepository{{Type: "helm", URL: "https://giantswarm.github.io/giantswarm-catalog/"}},
+				},
+				{
+					Name:         "giantswarm",
+					Namespace:    "giantswarm",
+					Title:        "giantswarm",
+					Description:  "giantswarm",
+					Repositories: []catalogRepository{{Type: "helm", URL: "https://giantswarm.github.io/giantswarm-catalog/"}},
+				},
+			},
+		},
+		{
+			name: "case 2: namespaced platform",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+- name: private
+  namespace: team-a
+  repositories:
+  - type: oci
+    url: oci://registry.example.com/charts/
+`,
+			instance:   "team-a",
+			namespaced: true,
+			expectedCatalogs: []catalog{
+				{
+					Name:         "giantswarm",
+					Namespace:    "giantswarm-team-a",
+					Title:        "giantswarm",
+					Description:  "giantswarm",
+					Repositories: []catalogRepository{{Type: "helm", URL: "https://giantswarm.github.io/giantswarm-catalog/"}},
+				},
+				{
+					Name:         "private",
+					Namespace:    "team-a",
+					Title:        "private",
+					Description:  "private",
+					Repositories: []catalogRepository{{Type: "oci", URL: "oci://registry.example.com/charts/"}},
+				},
+			},
+		},
+		{
+			name: "case 3: duplicate names in the namespaced platform namespace",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+- name: giantswarm
+  namespace: giantswarm
+  repositories:
+  - type: oci
+    url: oci://gsoci.azurecr.io/charts/giantswarm/
+`,
+			namespaced:   true,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 4: duplicate names",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+- name: giantswarm
+  namespace: default
+  repositories:
+  - type: oci
+    url: oci://gsoci.azurecr.io/charts/giantswarm/
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 5: unsupported repository type",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: git
+    url: https://github.com/giantswarm/giantswarm-catalog
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 6: missing helm URL",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: helm
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 7: missing oci URL",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: oci
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 8: oci URL without scheme",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: oci
+    url: gsoci.azurecr.io/charts/giantswarm/
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 9: missing name",
+			catalogs: `catalogs:
+- repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 10: no repositories",
+			catalogs: `catalogs:
+- name: giantswarm
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 11: config reference without namespace",
+			catalogs: `catalogs:
+- name: giantswarm
+  repositories:
+  - type: helm
+    url: https://giantswarm.github.io/giantswarm-catalog/
+  configMap:
+    name: giantswarm-catalog
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 12: unknown field",
+			catalogs: `catalogs:
+- name: giantswarm
+  url: https://giantswarm.github.io/giantswarm-catalog/
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "catalogs.yaml")
+			err := os.WriteFile(path, []byte(tc.catalogs), 0o600)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			catalogs, err := readCatalogs(path, tc.instance, tc.namespaced)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(catalogs, tc.expectedCatalogs) {
+				t.Fatalf("expected %#v got %#v", tc.expectedCatalogs, catalogs)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package bootstrap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	ociScheme = "oci://"
+)
+
+// chartReference is a chart in an OCI registry, e.g.
+// oci://gsoci.azurecr.io/charts/giantswarm/app-operator:6.7.0.
+type chartReference struct {
+	// Repository is the registry host, including a port if any, and path
+	// without the chart name, e.g. gsoci.azurecr.io/charts/giantswarm.
+	Repository string
+	Name       string
+	Version    string
+	// Digest pins the chart manifest, e.g. sha256:2c26b4.... It takes
+	// precedence over Version when pulling.
+	Digest digest.Digest
+}
+
+// parseChartReference parses an oci:// chart reference. The tag is optional
+// and defaults to defaultVersion so the pinned versions are kept when only a
+// registry is given. References may be pinned with a digest, e.g.
+// oci://localhost:5000/app-operator:6.7.0@sha256:2c26b4....
+func parseChartReference(ref, defaultVersion string) (chartReference, error) {
+	if !strings.HasPrefix(ref, ociScheme) {
+		return chartReference{}, microerror.Maskf(invalidFlagError, "chart reference %#q must start with %#q", ref, ociScheme)
+	}
+
+	trimmed := strings.TrimPrefix(ref, ociScheme)
+
+	var d digest.Digest
+	if i := strings.Index(trimmed, "@"); i >= 0 {
+		var err error
+		d, err = digest.Parse(trimmed[i+1:])
+		if err != nil {
+			return chartReference{}, microerror.Maskf(invalidFlagError, "chart reference %#q has an invalid digest: %s", ref, err)
+		}
+		trimmed = trimmed[:i]
+	}
+
+	// The registry host may contain a port, so the tag is only looked for
+	// in the last path segment.
+	i := strings.LastIndex(trimmed, "/")
+	if i <= 0 {
+		return chartReference{}, microerror.Maskf(invalidFlagError, "chart reference %#q must contain a registry and a chart name", ref)
+	}
+
+	c := chartReference{
+		Repository: trimmed[:i],
+		Name:       trimmed[i+1:],
+		Version:    defaultVersion,
+		Digest:     d,
+	}
+
+	if j := strings.LastIndex(c.Name, ":"); j >= 0 {
+		c.Version = c.Name[j+1:]
+		c.Name = c.Name[:j]
+	}
+
+	if c.Name == "" || c.Version == "" {
+		return chartReference{}, microerror.Maskf(invalidFlagError, "chart reference %#q must contain a chart name and version", ref)
+	}
+
+	return c, nil
+}
+
+// RepositoryURL returns the URL of the OCI repository the chart is in, as
+// used in Catalog CRs.
+func (c chartReference) RepositoryURL() string {
+	return fmt.Sprintf("%s%s/", ociScheme, c.Repository)
+}
+
+// URL returns the fully qualified reference of the chart version. Digests
+// are used instead of the tag since registry clients do not accept both.
+func (c chartReference) URL() string {
+	if c.Digest != "" {
+		return fmt.Sprintf("%s%s/%s@%s", ociScheme, c.Repository, c.Name, c.Digest)
+	}
+
+	return fmt.Sprintf("%s%s/%s:%s", ociScheme, c.Repository, c.Name, c.Version)
+}
@@ -0,0 +1,292 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	helmregistry "helm.sh/helm/v3/pkg/registry"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"oras.land/oras-go/pkg/content"
+)
+
+const (
+	testDigest = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+)
+
+func Test_parseChartReference(t *testing.T) {
+	testCases := []struct {
+		name          string
+		ref           string
+		expectedChart chartReference
+		expectedURL   string
+		errorMatcher  func(error) bool
+	}{
+		{
+			name: "case 0: tag",
+			ref:  "oci://gsoci.azurecr.io/charts/giantswarm/app-operator:6.7.0",
+			expectedChart: chartReference{
+				Repository: "gsoci.azurecr.io/charts/giantswarm",
+				Name:       "app-operator",
+				Version:    "6.7.0",
+			},
+			expectedURL: "oci://gsoci.azurecr.io/charts/giantswarm/app-operator:6.7.0",
+		},
+		{
+			name: "case 1: default version",
+			ref:  "oci://gsoci.azurecr.io/charts/giantswarm/app-operator",
+			expectedChart: chartReference{
+				Repository: "gsoci.azurecr.io/charts/giantswarm",
+				Name:       "app-operator",
+				Version:    "1.0.0",
+			},
+			expectedURL: "oci://gsoci.azurecr.io/charts/giantswarm/app-operator:1.0.0",
+		},
+		{
+			name: "case 2: registry with port and default version",
+			ref:  "oci://localhost:5000/app-operator",
+			expectedChart: chartReference{
+				Repository: "localhost:5000",
+				Name:       "app-operator",
+				Version:    "1.0.0",
+			},
+			expectedURL: "oci://localhost:5000/app-operator:1.0.0",
+		},
+		{
+			name: "case 3: registry with port and tag",
+			ref:  "oci://localhost:5000/charts/app-operator:6.7.0",
+			expectedChart: chartReference{
+				Repository: "localhost:5000/charts",
+				Name:       "app-operator",
+				Version:    "6.7.0",
+			},
+			expectedURL: "oci://localhost:5000/charts/app-operator:6.7.0",
+		},
+		{
+			name: "case 4: digest",
+			ref:  "oci://localhost:5000/app-operator@" + testDigest,
+			expectedChart: chartReference{
+				Repository: "localhost:5000",
+				Name:       "app-operator",
+				Version:    "1.0.0",
+				Digest:     testDigest,
+			},
+			expectedURL: "oci://localhost:5000/app-operator@" + testDigest,
+		},
+		{
+			name: "case 5: tag and digest",
+			ref:  "oci://gsoci.azurecr.io/charts/app-operator:6.7.0@" + testDigest,
+			expectedChart: chartReference{
+				Repository: "gsoci.azurecr.io/charts",
+				Name:       "app-operator",
+				Version:    "6.7.0",
+				Digest:     testDigest,
+			},
+			expectedURL: "oci://gsoci.azurecr.io/charts/app-operator@" + testDigest,
+		},
+		{
+			name:         "case 6: missing scheme",
+			ref:          "gsoci.azurecr.io/charts/app-operator:6.7.0",
+			errorMatcher: IsInvalidFlag,
+		},
+		{
+			name:         "case 7: missing chart name",
+			ref:          "oci://localhost:5000",
+			errorMatcher: IsInvalidFlag,
+		},
+		{
+			name:         "case 8: empty tag",
+			ref:          "oci://localhost:5000/app-operator:",
+			errorMatcher: IsInvalidFlag,
+		},
+		{
+			name:         "case 9: invalid digest",
+			ref:          "oci://localhost:5000/app-operator@sha256:abc",
+			errorMatcher: IsInvalidFlag,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chart, err := parseChartReference(tc.ref, "1.0.0")
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if chart != tc.expectedChart {
+				t.Fatalf("expected %#v got %#v", tc.expectedChart, chart)
+			}
+			if chart.URL() != tc.expectedURL {
+				t.Fatalf("expected %#v got %#v", tc.expectedURL, chart.URL())
+			}
+		})
+	}
+}
+
+func Test_pullChartReference(t *testing.T) {
+	tarball := []byte("app-operator chart tarball")
+	registry, manifestDigest := newTestRegistry(t, "charts/app-operator", "6.7.0", tarball)
+	defer registry.Close()
+
+	host := strings.TrimPrefix(registry.URL, "http://")
+
+	var helmClient helmclient.Interface
+	{
+		c := helmclient.Config{
+			K8sClient: fake.NewClientset(),
+			Logger:    microloggertest.New(),
+			RegistryOptions: &content.RegistryOptions{
+				PlainHTTP: true,
+			},
+			RestClient: &rest.RESTClient{},
+			RestConfig: &rest.Config{Host: registry.URL},
+		}
+
+		var err error
+		helmClient, err = helmclient.New(c)
+		if err != nil {
+			t.Fatalf("expected %#v got %#v", nil, err)
+		}
+	}
+
+	testCases := []struct {
+		name string
+		ref  string
+	}{
+		{
+			name: "case 0: tag",
+			ref:  "oci://" + host + "/charts/app-operator:6.7.0",
+		},
+		{
+			name: "case 1: default version",
+			ref:  "oci://" + host + "/charts/app-operator",
+		},
+		{
+			name: "case 2: digest",
+			ref:  "oci://" + host + "/charts/app-operator@" + manifestDigest.String(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chart, err := parseChartReference(tc.ref, "6.7.0")
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			path, err := helmClient.PullChartTarball(context.Background(), chart.URL())
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			defer func() { _ = os.Remove(path) }()
+
+			pulled, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			if !bytes.Equal(pulled, tarball) {
+				t.Fatalf("expected %q got %q", tarball, pulled)
+			}
+		})
+	}
+}
+
+// newTestRegistry serves a Helm chart manifest for repository under tag and
+// its digest, following the read-only part of the OCI distribution API.
+func newTestRegistry(t *testing.T, repository, tag string, tarball []byte) (*httptest.Server, digest.Digest) {
+	config := []byte(`{"name":"app-operator","version":"6.7.0"}`)
+
+	blobs := map[digest.Digest][]byte{
+		digest.FromBytes(config):  config,
+		digest.FromBytes(tarball): tarball,
+	}
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config: ocispec.Descriptor{
+			MediaType: helmregistry.ConfigMediaType,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []ocispec.Descriptor{
+			{
+				MediaType: helmregistry.ChartLayerMediaType,
+				Digest:    digest.FromBytes(tarball),
+				Size:      int64(len(tarball)),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	manifestDigest := digest.FromBytes(manifest)
+
+	manifests := map[string][]byte{
+		tag:                     manifest,
+		manifestDigest.String(): manifest,
+	}
+
+	serve := func(w http.ResponseWriter, r *http.Request, mediaType string, d digest.Digest, body []byte) {
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Docker-Content-Digest", d.String())
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(body)
+		}
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		manifestsPrefix := "/v2/" + repository + "/manifests/"
+		blobsPrefix := "/v2/" + repository + "/blobs/"
+
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, manifestsPrefix):
+			body, ok := manifests[strings.TrimPrefix(r.URL.Path, manifestsPrefix)]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			serve(w, r, ocispec.MediaTypeImageManifest, digest.FromBytes(body), body)
+		case strings.HasPrefix(r.URL.Path, blobsPrefix):
+			d := digest.Digest(strings.TrimPrefix(r.URL.Path, blobsPrefix))
+			body, ok := blobs[d]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			serve(w, r, "application/octet-stream", d, body)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(handler)), manifestDigest
+}
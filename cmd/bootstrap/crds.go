@@ -0,0 +1,202 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/backoff"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+
+	"github.com/giantswarm/apptestctl/pkg/await"
+	"github.com/giantswarm/apptestctl/pkg/crds"
+)
+
+const (
+	// crdWorkers bounds the CRDs created concurrently. Some CRDs are larger
+	// than 1 MB, so creating all of them at once puts needless load on the
+	// API server.
+	crdWorkers = 4
+)
+
+// ensureCRDs creates the embedded CRDs with a bounded worker pool and waits
+// until all of them are established and served, so the CRD phase takes as
+// long as the slowest CRD instead of the sum of all of them. The first error
+// cancels the remaining work.
+func (r *runner) ensureCRDs(ctx context.Context, k8sClients k8sclient.Interface) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	var established []*apiextensionsv1.CustomResourceDefinition
+	var firstErr error
+
+	jobs := make(chan crds.CRD)
+	for i := 0; i < crdWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for embedded := range jobs {
+				crd, err := r.ensureCRD(ctx, k8sClients, embedded)
+
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				} else if crd != nil {
+					established = append(established, crd)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, embedded := range crds.All() {
+		select {
+		case jobs <- embedded:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return microerror.Mask(firstErr)
+	}
+	if ctx.Err() != nil {
+		return microerror.Mask(ctx.Err())
+	}
+
+	err := r.waitForDiscovery(ctx, k8sClients, established)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// ensureCRD creates an embedded CRD and waits until it is established. It
+// returns the CRD as stored by the API server.
+func (r *runner) ensureCRD(ctx context.Context, k8sClients k8sclient.Interface, embedded crds.CRD) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, err := embedded.Decode()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "creating CRD %#q", crd.Name)
+
+	r.setMetadata(crd)
+
+	err = k8sClients.CtrlClient().Create(ctx, crd)
+	if apierrors.IsAlreadyExists(err) {
+		r.logger.Debugf(ctx, "%#q already exists", crd.Name)
+	} else if err != nil {
+		r.logger.Errorf(ctx, err, "failed to create CRD %#q", crd.Name)
+		return nil, microerror.Mask(err)
+	} else {
+		r.trackObject(k8sClients.CtrlClient(), fmt.Sprintf("CRD %s", crd.Name), crd)
+
+		r.logger.Debugf(ctx, "created %#q CRD", crd.Name)
+	}
+
+	crdName := crd.Name
+
+	r.logger.Debugf(ctx, "waiting for CRD %#q to be established", crdName)
+
+	var current apiextensionsv1.CustomResourceDefinition
+
+	isEstablished := func(ctx context.Context) (bool, error) {
+		err := k8sClients.CtrlClient().Get(ctx, types.NamespacedName{Name: crdName}, &current)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == apiextensionsv1.Established && condition.Status == apiextensionsv1.ConditionTrue {
+				return true, nil
+			}
+		}
+
+		return false, microerror.Maskf(executionFailedError, "CRD %#q is not established yet", crdName)
+	}
+	watchCRD := func(ctx context.Context) (watch.Interface, error) {
+		return k8sClients.ExtClient().ApiextensionsV1().CustomResourceDefinitions().Watch(ctx, await.NameSelector(crdName))
+	}
+
+	err = await.For(ctx, r.logger, fmt.Sprintf("CRD %s to be established", crdName), backoff.ShortMaxWait, await.FallbackInterval, isEstablished, watchCRD)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "CRD %#q is established", crdName)
+
+	return &current, nil
+}
+
+// waitForDiscovery blocks until the served versions of all CRDs appear in
+// API discovery. Installing the operator charts straight after creating the
+// CRDs races the API server's discovery refresh, making helm fail with
+// "resource mapping not found" for kinds the charts render based on
+// capabilities, e.g. VerticalPodAutoscaler. Every check fetches discovery
+// once for all CRDs.
+func (r *runner) waitForDiscovery(ctx context.Context, k8sClients k8sclient.Interface, crdList []*apiextensionsv1.CustomResourceDefinition) error {
+	r.logger.Debugf(ctx, "waiting for %d CRDs to be in API discovery", len(crdList))
+
+	discovered := func(ctx context.Context) (bool, error) {
+		_, lists, err := k8sClients.K8sClient().Discovery().ServerGroupsAndResources()
+		if discovery.IsGroupDiscoveryFailedError(err) {
+			// Groups failing discovery are reported as missing below.
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		served := map[string]bool{}
+		for _, list := range lists {
+			for _, resource := range list.APIResources {
+				served[fmt.Sprintf("%s/%s", list.GroupVersion, resource.Name)] = true
+			}
+		}
+
+		var missing []string
+		for _, crd := range crdList {
+			for _, version := range crd.Spec.Versions {
+				if !version.Served {
+					continue
+				}
+
+				groupVersion := fmt.Sprintf("%s/%s", crd.Spec.Group, version.Name)
+				if !served[fmt.Sprintf("%s/%s", groupVersion, crd.Spec.Names.Plural)] {
+					missing = append(missing, fmt.Sprintf("%s in %s", crd.Spec.Names.Plural, groupVersion))
+				}
+			}
+		}
+
+		if len(missing) > 0 {
+			return false, microerror.Maskf(executionFailedError, "not in API discovery yet: %s", strings.Join(missing, ", "))
+		}
+
+		return true, nil
+	}
+
+	err := await.For(ctx, r.logger, "CRDs to be in API discovery", backoff.ShortMaxWait, await.DiscoveryInterval, discovered, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "%d CRDs are in API discovery", len(crdList))
+
+	return nil
+}
@@ -23,3 +23,30 @@ var invalidFlagError = &microerror.Error{
 func IsInvalidFlag(err error) bool {
 	return microerror.Cause(err) == invalidFlagError
 }
+
+var missingClusterResourcesError = &microerror.Error{
+	Kind: "missingClusterResourcesError",
+}
+
+// IsMissingClusterResources asserts missingClusterResourcesError.
+func IsMissingClusterResources(err error) bool {
+	return microerror.Cause(err) == missingClusterResourcesError
+}
+
+var preflightFailedError = &microerror.Error{
+	Kind: "preflightFailedError",
+}
+
+// IsPreflightFailed asserts preflightFailedError.
+func IsPreflightFailed(err error) bool {
+	return microerror.Cause(err) == preflightFailedError
+}
+
+var unscopedChartError = &microerror.Error{
+	Kind: "unscopedChartError",
+}
+
+// IsUnscopedChart asserts unscopedChartError.
+func IsUnscopedChart(err error) bool {
+	return microerror.Cause(err) == unscopedChartError
+}
@@ -1,46 +1,131 @@
 package bootstrap
 
 import (
-	"os"
+	"strings"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/apptestctl/pkg/cache"
 )
 
 const (
-	installOperators = "install-operators"
-	kubeconfig       = "kubeconfig"
-	kubeconfigEnvVar = "KUBECONFIG"
-	kubeconfigPath   = "kubeconfig-path"
-	logLevel         = "log-level"
-	wait             = "wait"
+	appOperatorChart   = "app-operator-chart"
+	cacheDir           = "cache-dir"
+	catalogsFile       = "catalogs-file"
+	chartMuseumChart   = "chartmuseum-chart"
+	chartOperatorChart = "chart-operator-chart"
+	force              = "force"
+	installOperators   = "install-operators"
+	lockTimeout        = "lock-timeout"
+	logLevel           = "log-level"
+	registryConfig     = "registry-config"
+	registryPlainHTTP  = "registry-plain-http"
+	rollbackOnFailure  = "rollback-on-failure"
+	skipPreflight      = "skip-preflight"
+	wait               = "wait"
+
+	workloadCluster            = "workload-cluster"
+	workloadKubeConfig         = "workload-kubeconfig"
+	workloadKubeConfigInternal = "workload-kubeconfig-internal"
 )
 
 type flag struct {
-	InstallOperators bool
-	KubeConfig       string
-	KubeConfigPath   string
-	LogLevel         string
-	Wait             bool
+	AppOperatorChart   string
+	CacheDir           string
+	CatalogsFile       string
+	ChartMuseumChart   string
+	ChartOperatorChart string
+	Force              bool
+	InstallOperators   bool
+	LockTimeout        time.Duration
+	LogLevel           string
+	RegistryConfig     string
+	RegistryPlainHTTP  bool
+	RollbackOnFailure  bool
+	SkipPreflight      bool
+	Wait               bool
+
+	WorkloadCluster            string
+	WorkloadKubeConfig         string
+	WorkloadKubeConfigInternal string
 }
 
 func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.AppOperatorChart, appOperatorChart, "", "OCI reference of the app-operator chart, e.g. oci://gsoci.azurecr.io/charts/giantswarm/app-operator:6.7.0. Defaults to the control-plane-catalog Helm index.")
+	cmd.Flags().StringVar(&f.CacheDir, cacheDir, cache.DefaultDir(), "Directory for caching catalog indexes and chart tarballs")
+	cmd.Flags().StringVar(&f.CatalogsFile, catalogsFile, "", "Path to a YAML file declaring additional catalogs to create, see README")
+	cmd.Flags().StringVar(&f.ChartMuseumChart, chartMuseumChart, "", "OCI reference of the chartmuseum chart, e.g. oci://registry.example.com/charts/chartmuseum:3.9.3. Defaults to the chartmuseum Helm index.")
+	cmd.Flags().StringVar(&f.ChartOperatorChart, chartOperatorChart, "", "OCI reference of the chart-operator chart, e.g. oci://gsoci.azurecr.io/charts/giantswarm/chart-operator:2.35.0. Defaults to the control-plane-catalog Helm index.")
+	cmd.Flags().BoolVar(&f.Force, force, false, "Bootstrap even when the apptestctl-info ConfigMap records the same configuration and all components are healthy")
 	cmd.Flags().BoolVarP(&f.InstallOperators, installOperators, "o", true, "Install app-operator and chart-operator")
-	cmd.Flags().StringVarP(&f.KubeConfig, kubeconfig, "k", "", "Explicit kubeconfig for the target cluster")
-	cmd.Flags().StringVarP(&f.KubeConfigPath, kubeconfigPath, "p", "", "Path to a kubeconfig file for the target cluster")
+	cmd.Flags().DurationVar(&f.LockTimeout, lockTimeout, 10*time.Minute, "Maximum time to wait for another bootstrap or reset of the platform to finish")
 	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().StringVar(&f.RegistryConfig, registryConfig, "", "Path to a docker config file with credentials for OCI registries. Defaults to the docker config of the current user.")
+	cmd.Flags().BoolVar(&f.RegistryPlainHTTP, registryPlainHTTP, false, "Use plain HTTP to pull charts from OCI registries, e.g. a local registry")
+	cmd.Flags().BoolVar(&f.RollbackOnFailure, rollbackOnFailure, false, "Delete the resources and releases created by this run when bootstrap fails. Resources which existed before are kept.")
+	cmd.Flags().BoolVar(&f.SkipPreflight, skipPreflight, false, "Skip the preflight checks run before bootstrapping")
 	cmd.Flags().BoolVarP(&f.Wait, wait, "w", true, "Wait for all components to be ready")
+
+	cmd.Flags().StringVar(&f.WorkloadCluster, workloadCluster, "workload", "Name of the workload cluster, used for its namespace and kubeconfig Secret in the management cluster")
+	cmd.Flags().StringVar(&f.WorkloadKubeConfig, workloadKubeConfig, "", "Path to the kubeconfig of a workload cluster to install the CRDs and chart-operator into")
+	cmd.Flags().StringVar(&f.WorkloadKubeConfigInternal, workloadKubeConfigInternal, "", "Path to the workload cluster kubeconfig app-operator uses from inside the management cluster, e.g. from kind get kubeconfig --internal. Defaults to --workload-kubeconfig.")
 }
 
 func (f *flag) Validate() error {
-	if f.KubeConfig == "" && f.KubeConfigPath == "" && os.Getenv(kubeconfigEnvVar) == "" {
-		return microerror.Maskf(invalidFlagError, "either --%s or --%s or KUBECONFIG must be set", kubeconfig, kubeconfigPath)
-	} else if f.KubeConfig != "" && f.KubeConfigPath != "" {
-		return microerror.Maskf(invalidFlagError, "both --%s or --%s must not be set", kubeconfig, kubeconfigPath)
+	if f.CacheDir == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", cacheDir)
+	}
+	if f.AppOperatorChart != "" {
+		_, err := parseChartReference(f.AppOperatorChart, appOperatorVersion)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	if f.ChartMuseumChart != "" {
+		c, err := parseChartReference(f.ChartMuseumChart, chartMuseumVersion)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if c.Name != chartMuseumName {
+			return microerror.Maskf(invalidFlagError, "--%s must reference a chart named %#q", chartMuseumChart, chartMuseumName)
+		}
+		// app-operator pulls chartmuseum by the App CR's version, so the
+		// chart cannot be pinned by digest.
+		if c.Digest != "" {
+			return microerror.Maskf(invalidFlagError, "--%s must not contain a digest, app-operator pulls charts by tag", chartMuseumChart)
+		}
+	}
+	if f.ChartOperatorChart != "" {
+		_, err := parseChartReference(f.ChartOperatorChart, chartOperatorVersion)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	if f.LockTimeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be greater than 0", lockTimeout)
 	}
 	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
 		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
 	}
+	if f.WorkloadKubeConfig != "" {
+		errs := validation.IsDNS1123Label(f.WorkloadCluster)
+		if len(errs) > 0 {
+			return microerror.Maskf(invalidFlagError, "--%s must be a valid namespace name: %s", workloadCluster, strings.Join(errs, ", "))
+		}
+	}
+	if f.WorkloadKubeConfigInternal != "" && f.WorkloadKubeConfig == "" {
+		return microerror.Maskf(invalidFlagError, "--%s requires --%s", workloadKubeConfigInternal, workloadKubeConfig)
+	}
+	// Stdin can only be read once and is reserved for --kubeconfig.
+	if f.WorkloadKubeConfig == "-" {
+		return microerror.Maskf(invalidFlagError, "--%s must be a path, reading it from stdin is not supported", workloadKubeConfig)
+	}
+	if f.WorkloadKubeConfigInternal == "-" {
+		return microerror.Maskf(invalidFlagError, "--%s must be a path, reading it from stdin is not supported", workloadKubeConfigInternal)
+	}
 
 	return nil
 }
@@ -0,0 +1,258 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/crds"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/project"
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+const (
+	infoAppOperatorVersion   = "appOperatorVersion"
+	infoBootstrappedAt       = "bootstrappedAt"
+	infoChartMuseumVersion   = "chartMuseumVersion"
+	infoChartOperatorVersion = "chartOperatorVersion"
+	infoConfigDigest         = "configDigest"
+	infoCRDDigest            = "crdDigest"
+	infoGitSHA               = "gitSHA"
+	infoUpdatedAt            = "updatedAt"
+	infoVersion              = "version"
+)
+
+// desiredInfo returns the ConfigMap data identifying a bootstrap
+// configuration. Bootstraps with equal data install the same components.
+func (r *runner) desiredInfo(catalogs []catalog) (map[string]string, error) {
+	config := struct {
+		AppOperatorChart   string    `json:"appOperatorChart"`
+		Catalogs           []catalog `json:"catalogs"`
+		ChartMuseumChart   string    `json:"chartMuseumChart"`
+		ChartOperatorChart string    `json:"chartOperatorChart"`
+		Instance           string    `json:"instance"`
+		Namespaced         bool      `json:"namespaced"`
+	}{
+		AppOperatorChart:   r.flag.AppOperatorChart,
+		Catalogs:           catalogs,
+		ChartMuseumChart:   r.flag.ChartMuseumChart,
+		ChartOperatorChart: r.flag.ChartOperatorChart,
+		Instance:           r.cluster.Instance,
+		Namespaced:         r.cluster.Namespaced,
+	}
+
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sum := sha256.Sum256(bytes)
+
+	data := map[string]string{
+		infoConfigDigest: hex.EncodeToString(sum[:]),
+		infoCRDDigest:    crds.Digest(),
+		infoGitSHA:       project.GitSHA(),
+		infoVersion:      project.Version(),
+	}
+
+	return data, nil
+}
+
+// isBootstrapped returns true when the info ConfigMap records the desired
+// configuration and all components are healthy, so bootstrap has nothing to
+// do. The recorded CRD digest is not trusted on its own since CRDs may have
+// been deleted since, so the CRDs must be served as well. It must be called
+// with the lock held, otherwise another run may be changing the platform.
+func (r *runner) isBootstrapped(ctx context.Context, k8sClients k8sclient.Interface, helmClient helmclient.Interface, desired map[string]string) (bool, error) {
+	configMap, err := k8sClients.K8sClient().CoreV1().ConfigMaps(r.namespace()).Get(ctx, key.InfoConfigMapName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "configmap %#q not found", key.InfoConfigMapName())
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	for k, v := range desired {
+		if configMap.Data[k] != v {
+			r.logger.Debugf(ctx, "recorded %s %#q differs from %#q", k, configMap.Data[k], v)
+			return false, nil
+		}
+	}
+
+	missing, err := missingCRDs(k8sClients)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if len(missing) > 0 {
+		r.logger.Debugf(ctx, "missing %s", strings.Join(missing, ", "))
+		return false, nil
+	}
+
+	for _, releaseName := range []string{key.AppOperatorName(r.cluster.Instance), key.ChartOperatorName(r.cluster.Instance)} {
+		release, err := helmClient.GetReleaseContent(ctx, r.namespace(), releaseName)
+		if helmclient.IsReleaseNotFound(err) {
+			r.logger.Debugf(ctx, "release %#q not found", releaseName)
+			return false, nil
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		if release.Status != helmclient.StatusDeployed {
+			r.logger.Debugf(ctx, "release %#q is %#q", releaseName, release.Status)
+			return false, nil
+		}
+
+		available, err := r.isReleaseAvailable(ctx, k8sClients, releaseName)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+		if !available {
+			return false, nil
+		}
+	}
+
+	err = k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.appCRNamespace(), Name: chartMuseumName}, &v1alpha1.App{})
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "app cr '%s/%s' not found", r.appCRNamespace(), chartMuseumName)
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	deploy, err := k8sClients.K8sClient().AppsV1().Deployments(r.namespace()).Get(ctx, chartMuseumName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "deployment %#q not found", chartMuseumName)
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if replicas(deploy.Spec.Replicas) != deploy.Status.ReadyReplicas {
+		r.logger.Debugf(ctx, "deployment %#q has %d ready replicas", chartMuseumName, deploy.Status.ReadyReplicas)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// isReleaseAvailable returns true when all workloads rendered by the
+// release, e.g. the operator Deployment, exist and have all pods available.
+func (r *runner) isReleaseAvailable(ctx context.Context, k8sClients k8sclient.Interface, releaseName string) (bool, error) {
+	workloads, err := workload.FromRelease(ctx, k8sClients.K8sClient(), releaseName, r.namespace())
+	if workload.IsReleaseNotFound(err) {
+		r.logger.Debugf(ctx, "release %#q not found", releaseName)
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if len(workloads) == 0 {
+		r.logger.Debugf(ctx, "release %#q has no workloads", releaseName)
+		return false, nil
+	}
+
+	for _, w := range workloads {
+		available, desired, err := workload.Availability(ctx, k8sClients.K8sClient(), w)
+		if apierrors.IsNotFound(err) {
+			r.logger.Debugf(ctx, "%s of release %#q not found", w, releaseName)
+			return false, nil
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		if available < desired {
+			r.logger.Debugf(ctx, "%s of release %#q has %d of %d pods available", w, releaseName, available, desired)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// writeInfo records the desired configuration along with the versions
+// actually installed. The time of the first bootstrap is kept.
+func (r *runner) writeInfo(ctx context.Context, k8sClients k8sclient.Interface, helmClient helmclient.Interface, desired map[string]string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	data := map[string]string{
+		infoBootstrappedAt: now,
+		infoUpdatedAt:      now,
+	}
+	for k, v := range desired {
+		data[k] = v
+	}
+
+	versions := map[string]string{
+		infoAppOperatorVersion:   key.AppOperatorName(r.cluster.Instance),
+		infoChartOperatorVersion: key.ChartOperatorName(r.cluster.Instance),
+	}
+	for k, releaseName := range versions {
+		release, err := helmClient.GetReleaseContent(ctx, r.namespace(), releaseName)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		data[k] = release.Version
+	}
+
+	var app v1alpha1.App
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: r.appCRNamespace(), Name: chartMuseumName}, &app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	data[infoChartMuseumVersion] = app.Spec.Version
+
+	configMaps := k8sClients.K8sClient().CoreV1().ConfigMaps(r.namespace())
+
+	current, err := configMaps.Get(ctx, key.InfoConfigMapName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.InfoConfigMapName(),
+				Namespace: r.namespace(),
+			},
+			Data: data,
+		}
+
+		r.setMetadata(configMap)
+
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		if current.Data[infoBootstrappedAt] != "" {
+			data[infoBootstrappedAt] = current.Data[infoBootstrappedAt]
+		}
+		current.Data = data
+		r.setMetadata(current)
+
+		_, err = configMaps.Update(ctx, current, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	r.logger.Debugf(ctx, "recorded %s", formatInfo(data))
+
+	return nil
+}
+
+func formatInfo(data map[string]string) string {
+	return fmt.Sprintf("apptestctl %s (%s), app-operator %s, chart-operator %s, chartmuseum %s", data[infoVersion], data[infoGitSHA], data[infoAppOperatorVersion], data[infoChartOperatorVersion], data[infoChartMuseumVersion])
+}
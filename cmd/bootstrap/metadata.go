@@ -0,0 +1,49 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/metadata"
+)
+
+// setMetadata adds the labels and annotations marking obj as part of the app
+// platform of the selected instance. Existing ones are kept.
+func (r *runner) setMetadata(obj metav1.Object) {
+	metadata.Set(obj, key.PlatformLabels(r.cluster.Instance))
+}
+
+// labelChartMuseum adds the metadata to the App CR, Catalog CR and user
+// values ConfigMap the apptest library created for chartmuseum, which does
+// not support custom labels.
+func (r *runner) labelChartMuseum(ctx context.Context, k8sClients k8sclient.Interface, catalogName string) error {
+	for description, obj := range r.chartMuseumObjects(catalogName) {
+		err := metadata.Patch(ctx, k8sClients.CtrlClient(), obj, key.PlatformLabels(r.cluster.Instance))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "labelled %s", description)
+	}
+
+	return nil
+}
+
+// labelRelease adds the metadata to the release Secrets and rendered objects
+// of a release, since helmclient supports neither release labels nor post
+// renderers. Helm owns their managed-by label, so they only get the
+// apptestctl specific labels.
+func (r *runner) labelRelease(ctx context.Context, k8sClients k8sclient.Interface, namespace, releaseName string) error {
+	err := metadata.PatchRelease(ctx, k8sClients, namespace, releaseName, key.ReleaseLabels(r.cluster.Instance))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "labelled release '%s/%s'", namespace, releaseName)
+
+	return nil
+}
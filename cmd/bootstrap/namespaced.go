@@ -0,0 +1,259 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/apptestctl/pkg/crds"
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+var (
+	clusterRoleKindRegexp = regexp.MustCompile(`(?m)^(\s*kind:\s*)ClusterRole(Binding)?(\s*)$`)
+	// watchNamespaceRegexp matches the values operator charts scope their
+	// watches with, e.g. .Values.app.watchNamespace of app-operator.
+	watchNamespaceRegexp = regexp.MustCompile(`\.Values((?:\.[A-Za-z0-9_]+)*)\.watchNamespace\b`)
+)
+
+// verifyClusterResources checks the cluster scoped resources a namespaced
+// bootstrap cannot create itself. All missing resources are reported at once
+// so a cluster admin can create them in one go.
+func (r *runner) verifyClusterResources(ctx context.Context, k8sClients k8sclient.Interface) error {
+	missing, err := missingCRDs(k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = k8sClients.K8sClient().SchedulingV1().PriorityClasses().Get(ctx, priorityClassName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		missing = append(missing, fmt.Sprintf("PriorityClass %s", priorityClassName))
+	} else if apierrors.IsForbidden(err) {
+		_, _ = fmt.Fprintf(r.stdout, "cannot verify PriorityClass %s exists, not allowed to read priorityclasses\n", priorityClassName)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = k8sClients.K8sClient().CoreV1().Namespaces().Get(ctx, r.namespace(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		missing = append(missing, fmt.Sprintf("Namespace %s", r.namespace()))
+	} else if apierrors.IsForbidden(err) {
+		_, _ = fmt.Fprintf(r.stdout, "cannot verify Namespace %s exists, not allowed to read namespaces\n", r.namespace())
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(missing) > 0 {
+		for _, m := range missing {
+			_, _ = fmt.Fprintf(r.stdout, "missing %s\n", m)
+		}
+
+		command := "apptestctl bootstrap --install-operators=false"
+		if r.cluster.Instance != "" {
+			command = fmt.Sprintf("%s --instance %s", command, r.cluster.Instance)
+		}
+
+		return microerror.Maskf(missingClusterResourcesError, "%d cluster scoped resources are missing, ask a cluster admin to create them with %s: %s", len(missing), command, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// missingCRDs returns the served versions of the embedded CRDs which are not
+// in API discovery. CRDs are checked via API discovery since namespace
+// admins are usually not allowed to read CRDs.
+func missingCRDs(k8sClients k8sclient.Interface) ([]string, error) {
+	var missing []string
+
+	resources := map[string]map[string]bool{}
+	for _, crd := range crds.All() {
+		for _, version := range crd.Versions {
+			groupVersion := fmt.Sprintf("%s/%s", crd.Group, version)
+
+			served, ok := resources[groupVersion]
+			if !ok {
+				served = map[string]bool{}
+
+				list, err := k8sClients.K8sClient().Discovery().ServerResourcesForGroupVersion(groupVersion)
+				if apierrors.IsNotFound(err) {
+					// fall through
+				} else if err != nil {
+					return nil, microerror.Mask(err)
+				} else {
+					for _, resource := range list.APIResources {
+						served[resource.Name] = true
+					}
+				}
+
+				resources[groupVersion] = served
+			}
+
+			if !served[crd.Plural] {
+				missing = append(missing, fmt.Sprintf("CRD %s (%s)", crd.Name, version))
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// createNamespacedChartMuseum creates the chartmuseum App CR and its user
+// values in the platform namespace. The apptest library cannot be used since
+// it creates the catalog in the default namespace.
+func (r *runner) createNamespacedChartMuseum(ctx context.Context, k8sClients k8sclient.Interface, catalogName, version string) error {
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.UserValuesName(chartMuseumName),
+			Namespace: r.appCRNamespace(),
+		},
+		Data: map[string]string{
+			"values": chartMuseumValuesYAML,
+		},
+	}
+
+	r.setMetadata(configMap)
+
+	_, err := k8sClients.K8sClient().CoreV1().ConfigMaps(configMap.Namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		r.logger.Debugf(ctx, "configmap '%s/%s' already exists", configMap.Namespace, configMap.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		r.trackObject(k8sClients.CtrlClient(), fmt.Sprintf("configmap %s/%s", configMap.Namespace, configMap.Name), configMap)
+	}
+
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      chartMuseumName,
+			Namespace: r.appCRNamespace(),
+			Labels: map[string]string{
+				label.AppKubernetesName:  chartMuseumName,
+				label.AppOperatorVersion: key.UniqueAppOperatorVersion,
+			},
+		},
+		Spec: v1alpha1.AppSpec{
+			Catalog:          catalogName,
+			CatalogNamespace: r.catalogNamespace(),
+			KubeConfig: v1alpha1.AppSpecKubeConfig{
+				InCluster: true,
+			},
+			Name:      chartMuseumName,
+			Namespace: r.namespace(),
+			Version:   version,
+		},
+	}
+	app.Spec.UserConfig.ConfigMap.Name = configMap.Name
+	app.Spec.UserConfig.ConfigMap.Namespace = configMap.Namespace
+	r.setMetadata(app)
+
+	r.logger.Debugf(ctx, "creating %#q app cr", chartMuseumName)
+
+	err = k8sClients.CtrlClient().Create(ctx, app)
+	if apierrors.IsAlreadyExists(err) {
+		r.logger.Debugf(ctx, "%#q app cr already exists", chartMuseumName)
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		r.trackObject(k8sClients.CtrlClient(), fmt.Sprintf("app %s/%s", app.Namespace, app.Name), app)
+
+		r.logger.Debugf(ctx, "created %#q app cr", chartMuseumName)
+	}
+
+	return nil
+}
+
+// appCRNamespace is the namespace of the chartmuseum App CR and its user
+// values ConfigMap.
+func (r *runner) appCRNamespace() string {
+	return key.AppCRNamespace(r.cluster.Instance, r.cluster.Namespaced)
+}
+
+// catalogNamespace is the namespace of the Catalog CRs bootstrap creates.
+func (r *runner) catalogNamespace() string {
+	return key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced)
+}
+
+// namespacedChart rewrites the ClusterRoles and ClusterRoleBindings of the
+// chart at path into Roles and RoleBindings, which helm creates in the
+// release namespace. Roles do not allow the operator to list and watch
+// cluster wide, so the chart must template a watchNamespace value. The
+// returned values set all of them to namespace and must be merged into the
+// install values. The rewritten tarball is written to a temporary directory
+// the caller must remove.
+func namespacedChart(path, namespace string) (string, map[string]interface{}, error) {
+	chart, err := loader.Load(path)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	values := map[string]interface{}{}
+	for _, t := range chart.Templates {
+		t.Data = clusterRoleKindRegexp.ReplaceAll(t.Data, []byte("${1}Role${2}${3}"))
+
+		for _, match := range watchNamespaceRegexp.FindAllSubmatch(t.Data, -1) {
+			valuePath := strings.Split(strings.TrimPrefix(string(match[1]), "."), ".")
+			if valuePath[0] == "" {
+				valuePath = nil
+			}
+			setValue(values, append(valuePath, "watchNamespace"), namespace)
+		}
+	}
+
+	if len(values) == 0 {
+		return "", nil, microerror.Maskf(unscopedChartError, "chart %#q has no watchNamespace value, it cannot run with the Roles of a namespaced bootstrap", chart.Name())
+	}
+
+	dir, err := os.MkdirTemp("", "apptestctl-namespaced-")
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	namespacedPath, err := chartutil.Save(chart, dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, microerror.Mask(err)
+	}
+
+	return namespacedPath, values, nil
+}
+
+// setValue sets the nested value at path, creating the maps in between.
+// Values which are not maps are replaced.
+func setValue(values map[string]interface{}, path []string, value interface{}) {
+	for _, k := range path[:len(path)-1] {
+		next, ok := values[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			values[k] = next
+		}
+		values = next
+	}
+
+	values[path[len(path)-1]] = value
+}
+
+// mergeValues merges src into dst, recursing into maps present in both.
+func mergeValues(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcOK := v.(map[string]interface{})
+		dstMap, dstOK := dst[k].(map[string]interface{})
+		if srcOK && dstOK {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
@@ -0,0 +1,117 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+func Test_namespacedChart(t *testing.T) {
+	path, values, err := namespacedChart(filepath.Join("testdata", "scoped-operator"), "giantswarm-team-a")
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	defer func() { _ = os.RemoveAll(filepath.Dir(path)) }()
+
+	expectedValues := map[string]interface{}{
+		"operator": map[string]interface{}{
+			"watchNamespace": "giantswarm-team-a",
+		},
+	}
+	if !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("expected %#v got %#v", expectedValues, values)
+	}
+
+	chart, err := loader.Load(path)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      "scoped-operator",
+		Namespace: "giantswarm-team-a",
+	}
+	renderValues, err := chartutil.ToRenderValues(chart, values, options, nil)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	rendered, err := engine.Render(chart, renderValues)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	rbac := rendered["scoped-operator/templates/rbac.yaml"]
+	if strings.Contains(rbac, "ClusterRole") {
+		t.Fatalf("expected no cluster roles got %s", rbac)
+	}
+	for _, kind := range []string{"kind: Role\n", "kind: RoleBinding\n", "  kind: Role\n"} {
+		if !strings.Contains(rbac, kind) {
+			t.Fatalf("expected %q in %s", kind, rbac)
+		}
+	}
+
+	configMap := rendered["scoped-operator/templates/configmap.yaml"]
+	if !strings.Contains(configMap, `watchNamespace: "giantswarm-team-a"`) {
+		t.Fatalf("expected watch namespace in %s", configMap)
+	}
+}
+
+func Test_namespacedChart_unscoped(t *testing.T) {
+	_, _, err := namespacedChart(filepath.Join("testdata", "unscoped-operator"), "giantswarm")
+	if !IsUnscopedChart(err) {
+		t.Fatalf("expected %#v got %#v", unscopedChartError, err)
+	}
+}
+
+func Test_mergeValues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dst      map[string]interface{}
+		src      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "case 0: nested maps are merged",
+			dst: map[string]interface{}{
+				"app":      map[string]interface{}{"a": "1"},
+				"provider": "aws",
+			},
+			src: map[string]interface{}{
+				"app": map[string]interface{}{"watchNamespace": "giantswarm"},
+			},
+			expected: map[string]interface{}{
+				"app":      map[string]interface{}{"a": "1", "watchNamespace": "giantswarm"},
+				"provider": "aws",
+			},
+		},
+		{
+			name: "case 1: scalars are replaced by maps",
+			dst: map[string]interface{}{
+				"app": "x",
+			},
+			src: map[string]interface{}{
+				"app": map[string]interface{}{"watchNamespace": "giantswarm"},
+			},
+			expected: map[string]interface{}{
+				"app": map[string]interface{}{"watchNamespace": "giantswarm"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mergeValues(tc.dst, tc.src)
+
+			if !reflect.DeepEqual(tc.dst, tc.expected) {
+				t.Fatalf("expected %#v got %#v", tc.expected, tc.dst)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+const (
+	rollbackTimeout = 2 * time.Minute
+)
+
+// created is an object or release created by this bootstrap run, along with
+// how to remove it again.
+type created struct {
+	Description string
+	Delete      func(ctx context.Context) error
+}
+
+// track records a resource this run created. Resources which existed before
+// must not be tracked, so rollbacks never remove what other runs created.
+func (r *runner) track(description string, delete func(ctx context.Context) error) {
+	r.createdMutex.Lock()
+	defer r.createdMutex.Unlock()
+
+	r.created = append(r.created, created{
+		Description: description,
+		Delete:      delete,
+	})
+}
+
+// trackObject tracks an object created via the controller-runtime client.
+func (r *runner) trackObject(ctrlClient client.Client, description string, obj client.Object) {
+	r.track(description, func(ctx context.Context) error {
+		return ctrlClient.Delete(ctx, obj)
+	})
+}
+
+// chartMuseumObjects returns the objects the apptest library creates when
+// installing the chartmuseum App.
+func (r *runner) chartMuseumObjects(catalogName string) map[string]client.Object {
+	return map[string]client.Object{
+		fmt.Sprintf("app %s/%s", r.appCRNamespace(), chartMuseumName): &v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.appCRNamespace(), Name: chartMuseumName},
+		},
+		fmt.Sprintf("catalog %s/%s", r.catalogNamespace(), catalogName): &v1alpha1.Catalog{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.catalogNamespace(), Name: catalogName},
+		},
+		fmt.Sprintf("configmap %s/%s", r.appCRNamespace(), key.UserValuesName(chartMuseumName)): &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.appCRNamespace(), Name: key.UserValuesName(chartMuseumName)},
+		},
+	}
+}
+
+// existingChartMuseumResources returns the descriptions of the chartmuseum
+// objects which exist before the apptest library installs the App.
+func (r *runner) existingChartMuseumResources(ctx context.Context, k8sClients k8sclient.Interface, catalogName string) (map[string]bool, error) {
+	existing := map[string]bool{}
+	for description, obj := range r.chartMuseumObjects(catalogName) {
+		err := k8sClients.CtrlClient().Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		existing[description] = true
+	}
+
+	return existing, nil
+}
+
+// trackChartMuseumResources tracks the chartmuseum objects the apptest
+// library created, i.e. the ones not existing beforehand.
+func (r *runner) trackChartMuseumResources(k8sClients k8sclient.Interface, catalogName string, existing map[string]bool) {
+	for description, obj := range r.chartMuseumObjects(catalogName) {
+		if existing[description] {
+			continue
+		}
+
+		r.trackObject(k8sClients.CtrlClient(), description, obj)
+	}
+}
+
+// rollback removes the tracked resources in reverse order. Failures are
+// reported and the remaining resources are still removed. It uses its own
+// context since the run's context may be canceled by a signal.
+func (r *runner) rollback() {
+	if len(r.created) == 0 {
+		_, _ = fmt.Fprintln(r.stdout, "bootstrap failed, nothing to roll back")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	_, _ = fmt.Fprintf(r.stdout, "bootstrap failed, rolling back %d resources created by this run\n", len(r.created))
+
+	var failed int
+	for i := len(r.created) - 1; i >= 0; i-- {
+		c := r.created[i]
+
+		err := c.Delete(ctx)
+		if apierrors.IsNotFound(err) {
+			_, _ = fmt.Fprintf(r.stdout, "%s is already gone\n", c.Description)
+		} else if err != nil {
+			failed++
+			_, _ = fmt.Fprintf(r.stdout, "failed to delete %s: %s\n", c.Description, microerror.Pretty(err, false))
+		} else {
+			_, _ = fmt.Fprintf(r.stdout, "deleted %s\n", c.Description)
+		}
+	}
+
+	if failed > 0 {
+		_, _ = fmt.Fprintf(r.stdout, "rolled back %d of %d resources, remove the rest manually\n", len(r.created)-failed, len(r.created))
+		return
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "rolled back %d resources\n", len(r.created))
+
+	r.created = nil
+}
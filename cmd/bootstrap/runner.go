@@ -5,54 +5,63 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
-	"github.com/giantswarm/appcatalog"
 	"github.com/giantswarm/apptest"
 	"github.com/giantswarm/backoff"
 	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
-	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	schedulingv1 "k8s.io/api/scheduling/v1"
-	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	"k8s.io/apimachinery/pkg/watch"
+	"oras.land/oras-go/pkg/content"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
-	"github.com/giantswarm/apptestctl/pkg/crds"
+	"github.com/giantswarm/apptestctl/pkg/await"
+	"github.com/giantswarm/apptestctl/pkg/cache"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/lock"
+	"github.com/giantswarm/apptestctl/pkg/preflight"
 )
 
 const (
 	appOperatorVersion             = "6.7.0"
 	chartMuseumCatalogHelmIndexURL = "https://chartmuseum.github.io/charts"
-	chartMuseumCatalogName         = "apptestctl-chartmuseum"
-	chartMuseumCatalogStorageURL   = "http://chartmuseum:8080/"
 	chartMuseumName                = "chartmuseum"
 	chartMuseumVersion             = "3.9.3"
 	chartOperatorVersion           = "2.35.0"
 	controlPlaneCatalogStorageURL  = "https://giantswarm.github.io/control-plane-catalog/"
-	namespace                      = "giantswarm"
+	priorityClassName              = "giantswarm-critical"
 )
 
 type runner struct {
-	flag   *flag
-	logger micrologger.Logger
-	stdout io.Writer
-	stderr io.Writer
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+
+	// created is tracked for --rollback-on-failure. CRDs are created
+	// concurrently, so tracking is guarded by createdMutex.
+	created      []created
+	createdMutex sync.Mutex
 }
 
 type Patch struct {
@@ -62,13 +71,22 @@ type Patch struct {
 }
 
 func (r *runner) Run(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
 
-	err := r.flag.Validate()
+	err := r.cluster.Validate()
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if r.cluster.Namespaced && r.flag.WorkloadKubeConfig != "" {
+		return microerror.Maskf(invalidFlagError, "--namespaced and --%s are mutually exclusive", workloadKubeConfig)
+	}
+
 	err = r.run(ctx, cmd, args)
 	if err != nil {
 		return microerror.Mask(err)
@@ -77,9 +95,7 @@ func (r *runner) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
-	var err error
-
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) (err error) {
 	var logger micrologger.Logger
 	{
 		c := micrologger.ActivationLoggerConfig{
@@ -96,50 +112,23 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 		r.logger = logger
 	}
 
-	var kubeConfig string
-	var restConfig *rest.Config
-	{
-		if r.flag.KubeConfig != "" {
-			// Set kube config for passing to the apptest library.
-			kubeConfig = r.flag.KubeConfig
-
-			bytes := []byte(kubeConfig)
-			restConfig, err = clientcmd.RESTConfigFromKubeConfig(bytes)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-		} else if r.flag.KubeConfigPath != "" {
-			restConfig, err = clientcmd.BuildConfigFromFlags("", r.flag.KubeConfigPath)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-		} else if os.Getenv(kubeconfigEnvVar) != "" {
-			loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-			mergedConfig, err := loadingRules.Load()
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			json, err := runtime.Encode(clientcmdlatest.Codec, mergedConfig)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			bytes, err := yaml.JSONToYAML(json)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			// Set kube config for passing to the apptest library.
-			kubeConfig = string(bytes)
+	var catalogs []catalog
+	if r.flag.CatalogsFile != "" {
+		catalogs, err = readCatalogs(r.flag.CatalogsFile, r.cluster.Instance, r.cluster.Namespaced)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
 
-			restConfig, err = clientcmd.RESTConfigFromKubeConfig(bytes)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-		} else {
-			// Shouldn't happen but returning error just in case.
-			return microerror.Maskf(invalidConfigError, "KubeConfig and KubeConfigPath must not be empty at the same time")
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
 		}
 	}
 
@@ -147,7 +136,7 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 	{
 		c := k8sclient.ClientsConfig{
 			Logger:     r.logger,
-			RestConfig: restConfig,
+			RestConfig: targetCluster.RESTConfig(),
 		}
 		k8sClients, err = k8sclient.NewClients(c)
 		if err != nil {
@@ -155,15 +144,19 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 		}
 	}
 
-	var helmClient helmclient.Interface
+	helmClient, err := r.newHelmClient(k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var chartCache *cache.Cache
 	{
-		c := helmclient.Config{
-			K8sClient:  k8sClients.K8sClient(),
-			Logger:     r.logger,
-			RestClient: k8sClients.RESTClient(),
-			RestConfig: k8sClients.RESTConfig(),
+		c := cache.Config{
+			Logger: r.logger,
+
+			Dir: r.flag.CacheDir,
 		}
-		helmClient, err = helmclient.New(c)
+		chartCache, err = cache.New(c)
 		if err != nil {
 			return microerror.Mask(err)
 		}
@@ -172,8 +165,7 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 	var appTest apptest.Interface
 	{
 		c := apptest.Config{
-			KubeConfig:     kubeConfig,
-			KubeConfigPath: r.flag.KubeConfigPath,
+			KubeConfig: targetCluster.KubeConfig(),
 
 			Logger: logger,
 		}
@@ -183,22 +175,121 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 		}
 	}
 
-	_, _ = fmt.Fprintln(r.stdout, "bootstrapping app platform components")
-
-	err = r.ensureCRDs(ctx, k8sClients)
+	desiredInfo, err := r.desiredInfo(catalogs)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	err = r.ensurePriorityClass(ctx, k8sClients)
-	if err != nil {
-		return microerror.Mask(err)
+	// Namespaced bootstraps keep the lease in the platform namespace, so it
+	// is verified to exist before taking the lock. This only reads.
+	if r.cluster.Namespaced {
+		err = r.verifyClusterResources(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
-	err = r.ensureNamespace(ctx, k8sClients)
+	// The lock is taken before anything is written, so concurrent runs
+	// never see each other's half created resources.
+	var platformLock *lock.Lock
+	{
+		c := lock.Config{
+			K8sClient: k8sClients.K8sClient(),
+			Logger:    r.logger,
+			Stdout:    r.stdout,
+
+			Annotations: key.Annotations(),
+			Holder:      fmt.Sprintf("bootstrap on %s", lock.Identity()),
+			Labels:      key.Labels(r.cluster.Instance),
+			Name:        key.LockName(r.cluster.Instance),
+			Namespace:   key.LockNamespace(r.cluster.Instance, r.cluster.Namespaced),
+		}
+		platformLock, err = lock.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	ctx, err = platformLock.Acquire(ctx, r.flag.LockTimeout)
 	if err != nil {
 		return microerror.Mask(err)
 	}
+	defer func() {
+		err := platformLock.Release()
+		if err != nil {
+			r.logger.Errorf(context.Background(), err, "failed to release lock")
+		}
+	}()
+	// Losing the lock cancels ctx, which makes the running step fail with a
+	// context error. The cause tells users why.
+	defer func() {
+		if err != nil && lock.IsLockLost(context.Cause(ctx)) {
+			err = microerror.Mask(context.Cause(ctx))
+		}
+	}()
+	// The rollback runs while the lock is still held, so it never deletes
+	// resources another run relies on. Without the lock another run may
+	// already use what this run created, so nothing is rolled back.
+	defer func() {
+		if err == nil || !r.flag.RollbackOnFailure {
+			return
+		}
+		if lock.IsLockLost(context.Cause(ctx)) {
+			_, _ = fmt.Fprintln(r.stdout, "bootstrap lost the lock, not rolling back since another run may use the created resources")
+			return
+		}
+
+		r.rollback()
+	}()
+
+	// The fast path only covers the management cluster, so bootstraps
+	// setting up a workload cluster always run in full.
+	if !r.flag.Force && r.flag.InstallOperators && r.flag.WorkloadKubeConfig == "" {
+		bootstrapped, err := r.isBootstrapped(ctx, k8sClients, helmClient, desiredInfo)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if bootstrapped {
+			_, _ = fmt.Fprintln(r.stdout, "app platform is already bootstrapped with the same configuration and healthy, use --force to bootstrap anyway")
+			return nil
+		}
+	}
+
+	if r.flag.SkipPreflight {
+		_, _ = fmt.Fprintln(r.stdout, "skipping preflight checks")
+	} else {
+		err = r.runPreflight(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	_, _ = fmt.Fprintln(r.stdout, "bootstrapping app platform components")
+
+	if !r.cluster.Namespaced {
+		err = r.ensureCRDs(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = r.ensurePriorityClass(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = r.ensureNamespace(ctx, k8sClients, r.namespace())
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if r.flag.WorkloadKubeConfig != "" {
+		err = r.bootstrapWorkloadCluster(ctx, k8sClients, chartCache)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
 
 	// If --install-operators is false we stop here. This is useful when we
 	// don't want to use the pinned app-operator and chart-operator versions.
@@ -207,19 +298,24 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 		return nil
 	}
 
-	err = r.installOperators(ctx, helmClient, k8sClients)
+	err = r.installOperators(ctx, k8sClients, helmClient, chartCache)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	err = r.installCatalogs(ctx, k8sClients)
+	err = r.installCatalogs(ctx, k8sClients, catalogs)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	hasPSP, err := r.hasPSP(ctx, k8sClients)
-	if err != nil {
-		return microerror.Mask(err)
+	// PSPs are cluster scoped, so namespaced bootstraps rely on the
+	// cluster admin having granted chartmuseum one when they are enforced.
+	hasPSP := false
+	if !r.cluster.Namespaced {
+		hasPSP, err = r.hasPSP(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
 	err = r.ensureChartMuseumPSP(ctx, k8sClients, hasPSP)
@@ -227,7 +323,7 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 		return microerror.Mask(err)
 	}
 
-	err = r.installChartMuseum(ctx, appTest)
+	err = r.installChartMuseum(ctx, k8sClients, appTest)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -237,192 +333,127 @@ func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) err
 		return microerror.Mask(err)
 	}
 
+	// chart-operator installs the chartmuseum release, so its objects can
+	// only be labelled once it is ready.
+	err = r.labelRelease(ctx, k8sClients, r.namespace(), chartMuseumName)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.writeInfo(ctx, k8sClients, helmClient, desiredInfo)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	_, _ = fmt.Fprintln(r.stdout, "app platform components are ready")
 
 	return nil
 }
 
-// splitYAMLDocuments splits a YAML string containing multiple documents separated by "---"
-// into individual document strings. It handles edge cases like empty documents and
-// single-document strings.
-func splitYAMLDocuments(yamlContent string) []string {
-	var documents []string
-
-	// Split by YAML document separator
-	parts := strings.Split(yamlContent, "\n---\n")
-
-	for _, part := range parts {
-		// Also handle "---" at the start of content
-		subParts := strings.Split(part, "\n---")
-		for _, subPart := range subParts {
-			trimmed := strings.TrimSpace(subPart)
-			// Skip empty documents and standalone separators
-			if trimmed != "" && trimmed != "---" {
-				documents = append(documents, trimmed)
-			}
-		}
+func (r *runner) newHelmClient(k8sClients k8sclient.Interface) (helmclient.Interface, error) {
+	var configs []string
+	if r.flag.RegistryConfig != "" {
+		configs = append(configs, r.flag.RegistryConfig)
 	}
 
-	// Handle edge case where content doesn't have separators (single document)
-	if len(documents) == 0 {
-		trimmed := strings.TrimSpace(yamlContent)
-		if trimmed != "" {
-			documents = append(documents, trimmed)
-		}
+	c := helmclient.Config{
+		K8sClient: k8sClients.K8sClient(),
+		Logger:    r.logger,
+		RegistryOptions: &content.RegistryOptions{
+			Configs:   configs,
+			PlainHTTP: r.flag.RegistryPlainHTTP,
+		},
+		RestClient: k8sClients.RESTClient(),
+		RestConfig: k8sClients.RESTConfig(),
+	}
+	helmClient, err := helmclient.New(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	return documents
+	return helmClient, nil
 }
 
-func (r *runner) ensureCRDs(ctx context.Context, k8sClients k8sclient.Interface) error {
+func (r *runner) runPreflight(ctx context.Context, k8sClients k8sclient.Interface) error {
 	var err error
 
-	var crdNames []string
-
+	var p *preflight.Preflight
 	{
-		for _, crdYAML := range crds.CRDs() {
-			// Split the YAML content in case it contains multiple documents
-			documents := splitYAMLDocuments(crdYAML)
-
-			// Process each document separately
-			for _, document := range documents {
-				var crd apiextensionsv1.CustomResourceDefinition
-
-				err = yaml.Unmarshal([]byte(document), &crd)
-				if err != nil {
-					return microerror.Mask(err)
-				}
-
-				if crd.Name == "" {
-					continue
-				}
+		c := preflight.Config{
+			K8sClient: k8sClients.K8sClient(),
+			Logger:    r.logger,
 
-				crdNames = append(crdNames, crd.Name)
-
-				r.logger.Debugf(ctx, "creating CRD %#q", crd.Name)
-
-				err = k8sClients.CtrlClient().Create(ctx, &crd)
-				if apierrors.IsAlreadyExists(err) {
-					r.logger.Debugf(ctx, "%#q already exists", crd.Name)
-					continue
-				} else if err != nil {
-					r.logger.Errorf(ctx, err, "failed to create CRD %#q", crd.Name)
-					return microerror.Mask(err)
-				}
-
-				r.logger.Debugf(ctx, "created %#q CRD", crd.Name)
-			}
+			AppCRNamespace: r.appCRNamespace(),
+			Namespace:      r.namespace(),
+			Namespaced:     r.cluster.Namespaced,
+		}
+		p, err = preflight.New(c)
+		if err != nil {
+			return microerror.Mask(err)
 		}
 	}
 
-	err = r.waitForCRDs(ctx, k8sClients, crdNames)
+	_, _ = fmt.Fprintln(r.stdout, "running preflight checks")
+
+	results, err := p.Run(ctx)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	return nil
-}
-
-// waitForCRDs blocks until every CRD is established and its served versions
-// appear in API discovery. Installing the operator charts straight after
-// creating the CRDs races the API server's discovery refresh, making helm
-// fail with "resource mapping not found" for kinds the charts render based
-// on capabilities, e.g. VerticalPodAutoscaler.
-func (r *runner) waitForCRDs(ctx context.Context, k8sClients k8sclient.Interface, crdNames []string) error {
-	for _, crdName := range crdNames {
-		r.logger.Debugf(ctx, "waiting for CRD %#q to be established", crdName)
-
-		o := func() error {
-			var crd apiextensionsv1.CustomResourceDefinition
-			err := k8sClients.CtrlClient().Get(ctx, types.NamespacedName{Name: crdName}, &crd)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			established := false
-			for _, condition := range crd.Status.Conditions {
-				if condition.Type == apiextensionsv1.Established && condition.Status == apiextensionsv1.ConditionTrue {
-					established = true
-				}
-			}
-			if !established {
-				return microerror.Maskf(executionFailedError, "CRD %#q is not established yet", crdName)
-			}
-
-			for _, version := range crd.Spec.Versions {
-				if !version.Served {
-					continue
-				}
-
-				groupVersion := fmt.Sprintf("%s/%s", crd.Spec.Group, version.Name)
-
-				resources, err := k8sClients.K8sClient().Discovery().ServerResourcesForGroupVersion(groupVersion)
-				if err != nil {
-					return microerror.Maskf(executionFailedError, "%#q is not in API discovery yet", groupVersion)
-				}
-
-				found := false
-				for _, resource := range resources.APIResources {
-					if resource.Name == crd.Spec.Names.Plural {
-						found = true
-					}
-				}
-				if !found {
-					return microerror.Maskf(executionFailedError, "%#q is not in API discovery for %#q yet", crd.Spec.Names.Plural, groupVersion)
-				}
-			}
-
-			return nil
-		}
-		b := backoff.NewExponential(backoff.ShortMaxWait, backoff.ShortMaxInterval)
+	results.Print(r.stdout)
 
-		err := backoff.Retry(o, b)
-		if err != nil {
-			return microerror.Mask(err)
-		}
-
-		r.logger.Debugf(ctx, "CRD %#q is established", crdName)
+	failed := results.Failed()
+	if len(failed) > 0 {
+		return microerror.Maskf(preflightFailedError, "%d preflight checks failed, use --%s to bootstrap anyway", len(failed), skipPreflight)
 	}
 
 	return nil
 }
 
-func (r *runner) ensureNamespace(ctx context.Context, k8sClients k8sclient.Interface) error {
-	namespace := "giantswarm"
-
+func (r *runner) ensureNamespace(ctx context.Context, k8sClients k8sclient.Interface, namespace string) error {
 	r.logger.Debugf(ctx, "ensuring namespace %#q", namespace)
 
-	o := func() error {
+	// The namespace is created again when a previous one is still
+	// terminating, so the watch also reacts to its deletion.
+	active := func(ctx context.Context) (bool, error) {
 		{
 			n := &v1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: namespace,
 				},
 			}
+			r.setMetadata(n)
+
 			_, err := k8sClients.K8sClient().CoreV1().Namespaces().Create(ctx, n, metav1.CreateOptions{})
 			if apierrors.IsAlreadyExists(err) {
 				r.logger.Debugf(ctx, "namespace %#q already exists", namespace)
 				// fall through
 			} else if err != nil {
-				return microerror.Mask(err)
+				return false, microerror.Mask(err)
+			} else {
+				r.track(fmt.Sprintf("namespace %s", namespace), func(ctx context.Context) error {
+					return k8sClients.K8sClient().CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+				})
 			}
 		}
 
 		{
 			n, err := k8sClients.K8sClient().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
 			if err != nil {
-				return microerror.Mask(err)
+				return false, microerror.Mask(err)
 			}
 			if n.Status.Phase != v1.NamespaceActive {
-				return microerror.Maskf(executionFailedError, "namespace in status %#q", n.Status.Phase)
+				return false, microerror.Maskf(executionFailedError, "namespace in status %#q", n.Status.Phase)
 			}
 		}
 
-		return nil
+		return true, nil
+	}
+	watchNamespace := func(ctx context.Context) (watch.Interface, error) {
+		return k8sClients.K8sClient().CoreV1().Namespaces().Watch(ctx, await.NameSelector(namespace))
 	}
-	b := backoff.NewExponential(backoff.ShortMaxWait, backoff.ShortMaxInterval)
 
-	err := backoff.Retry(o, b)
+	err := await.For(ctx, r.logger, fmt.Sprintf("namespace %s to be active", namespace), backoff.ShortMaxWait, await.FallbackInterval, active, watchNamespace)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -433,8 +464,6 @@ func (r *runner) ensureNamespace(ctx context.Context, k8sClients k8sclient.Inter
 }
 
 func (r *runner) ensurePriorityClass(ctx context.Context, k8sClients k8sclient.Interface) error {
-	priorityClassName := "giantswarm-critical"
-
 	r.logger.Debugf(ctx, "creating priorityclass %#q", priorityClassName)
 
 	pc := &schedulingv1.PriorityClass{
@@ -446,6 +475,8 @@ func (r *runner) ensurePriorityClass(ctx context.Context, k8sClients k8sclient.I
 		Description:   "This priority class is used by giantswarm kubernetes components.",
 	}
 
+	r.setMetadata(pc)
+
 	_, err := k8sClients.K8sClient().SchedulingV1().PriorityClasses().Create(ctx, pc, metav1.CreateOptions{})
 	if apierrors.IsAlreadyExists(err) {
 		r.logger.Debugf(ctx, "priorityclass %#q already exists", priorityClassName)
@@ -453,55 +484,175 @@ func (r *runner) ensurePriorityClass(ctx context.Context, k8sClients k8sclient.I
 	} else if err != nil {
 		return microerror.Mask(err)
 	} else {
+		r.track(fmt.Sprintf("priorityclass %s", priorityClassName), func(ctx context.Context) error {
+			return k8sClients.K8sClient().SchedulingV1().PriorityClasses().Delete(ctx, priorityClassName, metav1.DeleteOptions{})
+		})
+
 		r.logger.Debugf(ctx, "created priorityclass %#q", priorityClassName)
 	}
 
 	return nil
 }
 
-func (r *runner) installCatalogs(ctx context.Context, k8sClients k8sclient.Interface) error {
+// installCatalogs creates the chartmuseum catalog and the additional
+// catalogs declared via --catalogs-file. With --wait it blocks until
+// app-operator created AppCatalogEntries for each declared catalog.
+func (r *runner) installCatalogs(ctx context.Context, k8sClients k8sclient.Interface, catalogs []catalog) error {
 	var err error
 
-	catalogs := map[string]string{
-		chartMuseumName: chartMuseumCatalogStorageURL,
+	all := []catalog{
+		{
+			Name:        key.ChartMuseumCatalogName(r.cluster.Instance),
+			Namespace:   r.catalogNamespace(),
+			Title:       key.ChartMuseumCatalogName(r.cluster.Instance),
+			Description: key.ChartMuseumCatalogName(r.cluster.Instance),
+			Repositories: []catalogRepository{
+				{
+					Type: repositoryTypeHelm,
+					URL:  r.chartMuseumStorageURL(),
+				},
+			},
+		},
 	}
+	all = append(all, catalogs...)
 
-	for name, url := range catalogs {
-		r.logger.Debugf(ctx, "creating %#q catalog cr", name)
+	for _, c := range all {
+		r.logger.Debugf(ctx, "creating %#q catalog cr", c.Name)
 
-		catalogCR := &v1alpha1.Catalog{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: metav1.NamespaceDefault,
-			},
-			Spec: v1alpha1.CatalogSpec{
-				Description: name,
-				Title:       name,
-				Storage: v1alpha1.CatalogSpecStorage{
-					Type: "helm",
-					URL:  url,
-				},
-				Repositories: []v1alpha1.CatalogSpecRepository{
-					{
-						Type: "helm",
-						URL:  url,
-					},
-				},
-			},
+		catalogCR, err := r.newCatalogCR(ctx, k8sClients, c)
+		if err != nil {
+			return microerror.Mask(err)
 		}
+
 		err = k8sClients.CtrlClient().Create(ctx, catalogCR)
 		if apierrors.IsAlreadyExists(err) {
 			r.logger.Debugf(ctx, "%#q catalog CR already exists", catalogCR.Name)
 		} else if err != nil {
 			return microerror.Mask(err)
+		} else {
+			r.trackObject(k8sClients.CtrlClient(), fmt.Sprintf("catalog %s/%s", catalogCR.Namespace, catalogCR.Name), catalogCR)
 		}
 
-		r.logger.Debugf(ctx, "created %#q catalog cr", name)
+		r.logger.Debugf(ctx, "created %#q catalog cr", c.Name)
+	}
+
+	if !r.flag.Wait {
+		return nil
+	}
+
+	for _, c := range catalogs {
+		err = r.waitForAppCatalogEntries(ctx, k8sClients, c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
 	return nil
 }
 
+func (r *runner) newCatalogCR(ctx context.Context, k8sClients k8sclient.Interface, c catalog) (*v1alpha1.Catalog, error) {
+	labels := map[string]string{}
+	if c.Type != "" {
+		labels[label.CatalogType] = c.Type
+	}
+	if c.Visibility != "" {
+		labels[label.CatalogVisibility] = c.Visibility
+	}
+
+	var repositories []v1alpha1.CatalogSpecRepository
+	for _, repo := range c.Repositories {
+		repositories = append(repositories, v1alpha1.CatalogSpecRepository{
+			Type: repo.Type,
+			URL:  repo.URL,
+		})
+	}
+
+	var config *v1alpha1.CatalogSpecConfig
+	if c.ConfigMap != nil || c.Secret != nil {
+		config = &v1alpha1.CatalogSpecConfig{}
+	}
+	if c.ConfigMap != nil {
+		_, err := k8sClients.K8sClient().CoreV1().ConfigMaps(c.ConfigMap.Namespace).Get(ctx, c.ConfigMap.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, microerror.Maskf(executionFailedError, "configmap '%s/%s' referenced by catalog %#q not found", c.ConfigMap.Namespace, c.ConfigMap.Name, c.Name)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		config.ConfigMap = &v1alpha1.CatalogSpecConfigConfigMap{
+			Name:      c.ConfigMap.Name,
+			Namespace: c.ConfigMap.Namespace,
+		}
+	}
+	if c.Secret != nil {
+		_, err := k8sClients.K8sClient().CoreV1().Secrets(c.Secret.Namespace).Get(ctx, c.Secret.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, microerror.Maskf(executionFailedError, "secret '%s/%s' referenced by catalog %#q not found", c.Secret.Namespace, c.Secret.Name, c.Name)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		config.Secret = &v1alpha1.CatalogSpecConfigSecret{
+			Name:      c.Secret.Name,
+			Namespace: c.Secret.Namespace,
+		}
+	}
+
+	catalogCR := &v1alpha1.Catalog{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.Name,
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+		Spec: v1alpha1.CatalogSpec{
+			Config:      config,
+			Description: c.Description,
+			Title:       c.Title,
+			// Storage is deprecated in favour of Repositories but still
+			// set for older app-operator versions.
+			Storage: v1alpha1.CatalogSpecStorage{
+				Type: repositories[0].Type,
+				URL:  repositories[0].URL,
+			},
+			Repositories: repositories,
+		},
+	}
+
+	r.setMetadata(catalogCR)
+
+	return catalogCR, nil
+}
+
+func (r *runner) waitForAppCatalogEntries(ctx context.Context, k8sClients k8sclient.Interface, c catalog) error {
+	r.logger.Debugf(ctx, "waiting for appcatalogentries of %#q catalog", c.Name)
+
+	hasEntries := func(ctx context.Context) (bool, error) {
+		var entries v1alpha1.AppCatalogEntryList
+		err := k8sClients.CtrlClient().List(ctx, &entries, client.InNamespace(c.Namespace), client.MatchingLabels{label.CatalogName: c.Name})
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		return len(entries.Items) > 0, nil
+	}
+
+	watchEntries := func(ctx context.Context) (watch.Interface, error) {
+		resource := v1alpha1.SchemeGroupVersion.WithResource("appcatalogentries")
+		return k8sClients.DynClient().Resource(resource).Namespace(c.Namespace).Watch(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", label.CatalogName, c.Name),
+		})
+	}
+
+	err := await.For(ctx, r.logger, fmt.Sprintf("appcatalogentries of catalog %s", c.Name), 5*time.Minute, await.FallbackInterval, hasEntries, watchEntries)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "waited for appcatalogentries of %#q catalog", c.Name)
+
+	return nil
+}
+
 func (r *runner) hasPSP(ctx context.Context, k8sClients k8sclient.Interface) (bool, error) {
 
 	list, err := k8sClients.K8sClient().Discovery().ServerGroups()
@@ -523,7 +674,11 @@ func (r *runner) hasPSP(ctx context.Context, k8sClients k8sclient.Interface) (bo
 }
 
 func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.Interface, installPSP bool) error {
-	name := "chartmuseum-psp"
+	// The chart names its PSP after the release, which is the same for all
+	// instances. The RBAC granting it is cluster scoped, so its name is
+	// unique per instance.
+	pspName := key.ChartMuseumName() + "-psp"
+	name := key.ChartMuseumCatalogName(r.cluster.Instance) + "-psp"
 	r.logger.Debugf(ctx, "ensuring additional chartmuseum resources %#q", name)
 
 	o := func() error {
@@ -537,17 +692,23 @@ func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.
 						{
 							APIGroups:     []string{"extensions"},
 							Resources:     []string{"podsecuritypolicies"},
-							ResourceNames: []string{name},
+							ResourceNames: []string{pspName},
 							Verbs:         []string{"use"},
 						},
 					},
 				}
+				r.setMetadata(clusterRole)
+
 				_, err := k8sClients.K8sClient().RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{})
 				if apierrors.IsAlreadyExists(err) {
 					r.logger.Debugf(ctx, "clusterRole %#q already exists", name)
 					// fall through
 				} else if err != nil {
 					return microerror.Mask(err)
+				} else {
+					r.track(fmt.Sprintf("clusterrole %s", name), func(ctx context.Context) error {
+						return k8sClients.K8sClient().RbacV1().ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{})
+					})
 				}
 			}
 			{
@@ -559,7 +720,7 @@ func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.
 						{
 							Kind:      "ServiceAccount",
 							Name:      "chartmuseum",
-							Namespace: namespace,
+							Namespace: r.namespace(),
 						},
 					},
 					RoleRef: rbacv1.RoleRef{
@@ -568,12 +729,18 @@ func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.
 						APIGroup: "rbac.authorization.k8s.io",
 					},
 				}
+				r.setMetadata(clusterRoleBinding)
+
 				_, err := k8sClients.K8sClient().RbacV1().ClusterRoleBindings().Create(ctx, clusterRoleBinding, metav1.CreateOptions{})
 				if apierrors.IsAlreadyExists(err) {
 					r.logger.Debugf(ctx, "clusterRoleBinding %#q already exists", name)
 					// fall through
 				} else if err != nil {
 					return microerror.Mask(err)
+				} else {
+					r.track(fmt.Sprintf("clusterrolebinding %s", name), func(ctx context.Context) error {
+						return k8sClients.K8sClient().RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{})
+					})
 				}
 			}
 		}
@@ -585,7 +752,7 @@ func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.
 			np := &networkingv1.NetworkPolicy{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "chartmuseum",
-					Namespace: namespace,
+					Namespace: r.namespace(),
 				},
 				Spec: networkingv1.NetworkPolicySpec{
 					PodSelector: metav1.LabelSelector{
@@ -611,12 +778,18 @@ func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.
 					},
 				},
 			}
-			_, err := k8sClients.K8sClient().NetworkingV1().NetworkPolicies(namespace).Create(ctx, np, metav1.CreateOptions{})
+			r.setMetadata(np)
+
+			_, err := k8sClients.K8sClient().NetworkingV1().NetworkPolicies(r.namespace()).Create(ctx, np, metav1.CreateOptions{})
 			if apierrors.IsAlreadyExists(err) {
-				r.logger.Debugf(ctx, "networkpolicy %#q already exists", name)
+				r.logger.Debugf(ctx, "networkpolicy %#q already exists", np.Name)
 				// fall through
 			} else if err != nil {
 				return microerror.Mask(err)
+			} else {
+				r.track(fmt.Sprintf("networkpolicy %s/%s", np.Namespace, np.Name), func(ctx context.Context) error {
+					return k8sClients.K8sClient().NetworkingV1().NetworkPolicies(np.Namespace).Delete(ctx, np.Name, metav1.DeleteOptions{})
+				})
 			}
 		}
 
@@ -634,21 +807,65 @@ func (r *runner) ensureChartMuseumPSP(ctx context.Context, k8sClients k8sclient.
 	return nil
 }
 
-func (r *runner) installChartMuseum(ctx context.Context, appTest apptest.Interface) error {
+func (r *runner) installChartMuseum(ctx context.Context, k8sClients k8sclient.Interface, appTest apptest.Interface) error {
 	var err error
 
+	catalogName := key.ChartMuseumChartCatalogName(r.cluster.Instance, false)
+	catalogType := repositoryTypeHelm
+	catalogURL := chartMuseumCatalogHelmIndexURL
+	version := chartMuseumVersion
+
+	if r.flag.ChartMuseumChart != "" {
+		chart, err := parseChartReference(r.flag.ChartMuseumChart, chartMuseumVersion)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		catalogName = key.ChartMuseumChartCatalogName(r.cluster.Instance, true)
+		catalogType = repositoryTypeOCI
+		catalogURL = chart.RepositoryURL()
+		version = chart.Version
+	}
+
+	// The apptest library only creates helm catalogs in the default
+	// namespace, so other catalogs are created upfront and InstallApps
+	// reuses them.
+	if catalogType != repositoryTypeHelm || r.cluster.Namespaced {
+		err = r.ensureCatalog(ctx, k8sClients, catalogName, catalogType, catalogURL)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if r.cluster.Namespaced {
+		err = r.createNamespacedChartMuseum(ctx, k8sClients, catalogName, version)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
 	{
 		r.logger.Debugf(ctx, "creating %#q app cr", chartMuseumName)
 
+		// The apptest library silently reuses existing resources, so only
+		// the ones missing beforehand are tracked for rollbacks.
+		existing, err := r.existingChartMuseumResources(ctx, k8sClients, catalogName)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
 		apps := []apptest.App{
 			{
-				CatalogName:   chartMuseumCatalogName,
-				CatalogURL:    chartMuseumCatalogHelmIndexURL,
-				Name:          chartMuseumName,
-				Namespace:     namespace,
-				ValuesYAML:    chartMuseumValuesYAML,
-				Version:       chartMuseumVersion,
-				WaitForDeploy: r.flag.Wait,
+				AppCRNamespace: r.appCRNamespace(),
+				CatalogName:    catalogName,
+				CatalogURL:     catalogURL,
+				Name:           chartMuseumName,
+				Namespace:      r.namespace(),
+				ValuesYAML:     chartMuseumValuesYAML,
+				Version:        version,
+				WaitForDeploy:  r.flag.Wait,
 			},
 		}
 		err = appTest.InstallApps(ctx, apps)
@@ -656,13 +873,55 @@ func (r *runner) installChartMuseum(ctx context.Context, appTest apptest.Interfa
 			return microerror.Mask(err)
 		}
 
+		r.trackChartMuseumResources(k8sClients, catalogName, existing)
+
+		err = r.labelChartMuseum(ctx, k8sClients, catalogName)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
 		r.logger.Debugf(ctx, "created %#q app cr", chartMuseumName)
 	}
 
 	return nil
 }
 
-func (r *runner) installOperators(ctx context.Context, helmClient helmclient.Interface, k8sClients k8sclient.Interface) error {
+func (r *runner) ensureCatalog(ctx context.Context, k8sClients k8sclient.Interface, name, repositoryType, url string) error {
+	r.logger.Debugf(ctx, "creating %#q catalog cr", name)
+
+	c := catalog{
+		Name:        name,
+		Namespace:   r.catalogNamespace(),
+		Title:       name,
+		Description: name,
+		Repositories: []catalogRepository{
+			{
+				Type: repositoryType,
+				URL:  url,
+			},
+		},
+	}
+
+	catalogCR, err := r.newCatalogCR(ctx, k8sClients, c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = k8sClients.CtrlClient().Create(ctx, catalogCR)
+	if apierrors.IsAlreadyExists(err) {
+		r.logger.Debugf(ctx, "%#q catalog CR already exists", catalogCR.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		r.trackObject(k8sClients.CtrlClient(), fmt.Sprintf("catalog %s/%s", catalogCR.Namespace, catalogCR.Name), catalogCR)
+
+		r.logger.Debugf(ctx, "created %#q catalog cr", name)
+	}
+
+	return nil
+}
+
+func (r *runner) installOperators(ctx context.Context, k8sClients k8sclient.Interface, helmClient helmclient.Interface, chartCache *cache.Cache) error {
 	var err error
 
 	operators := map[string]string{
@@ -670,8 +929,18 @@ func (r *runner) installOperators(ctx context.Context, helmClient helmclient.Int
 		"chart-operator": chartOperatorVersion,
 	}
 
+	charts := map[string]string{
+		"app-operator":   r.flag.AppOperatorChart,
+		"chart-operator": r.flag.ChartOperatorChart,
+	}
+
+	releases := map[string]string{
+		"app-operator":   key.AppOperatorName(r.cluster.Instance),
+		"chart-operator": key.ChartOperatorName(r.cluster.Instance),
+	}
+
 	for name, version := range operators {
-		err = r.installOperator(ctx, helmClient, name, version)
+		err = r.installOperator(ctx, k8sClients, helmClient, chartCache, name, version, charts[name], releases[name], r.namespace(), r.operatorValues(name))
 		if err != nil {
 			return microerror.Mask(err)
 		}
@@ -681,38 +950,86 @@ func (r *runner) installOperators(ctx context.Context, helmClient helmclient.Int
 	return nil
 }
 
-func (r *runner) installOperator(ctx context.Context, helmClient helmclient.Interface, name, version string) error {
+// installOperator installs the operator chart from the control-plane-catalog
+// Helm index or, when chartRef is set, from an OCI registry. The values are
+// merged over the defaults all operators get.
+func (r *runner) installOperator(ctx context.Context, k8sClients k8sclient.Interface, helmClient helmclient.Interface, chartCache *cache.Cache, name, version, chartRef, releaseName, namespace string, values map[string]interface{}) error {
 	var operatorTarballPath string
-	{
+	if chartRef != "" {
+		chart, err := parseChartReference(chartRef, version)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "pulling tarball %#q", chart.URL())
+
+		// OCI tags are mutable, so charts pulled from registries are not
+		// cached.
+		operatorTarballPath, err = helmClient.PullChartTarball(ctx, chart.URL())
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		// The path is passed since operatorTarballPath is replaced by the
+		// namespaced copy below.
+		defer func(path string) {
+			err := os.Remove(path)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "deletion of %#q failed", path)
+			}
+		}(operatorTarballPath)
+
+		r.logger.Debugf(ctx, "tarball path is %#q", operatorTarballPath)
+	} else {
 		r.logger.Debugf(ctx, "getting tarball URL for %#q", name)
 
-		operatorTarballURL, err := appcatalog.GetLatestChart(ctx, controlPlaneCatalogStorageURL, name, version)
+		chart, err := chartCache.LatestChart(ctx, controlPlaneCatalogStorageURL, name, version)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
-		r.logger.Debugf(ctx, "tarball URL is %#q", operatorTarballURL)
+		operatorTarballURL := chart.URLs[0]
 
-		r.logger.Debugf(ctx, "pulling tarball")
+		r.logger.Debugf(ctx, "tarball URL is %#q", operatorTarballURL)
 
-		operatorTarballPath, err = helmClient.PullChartTarball(ctx, operatorTarballURL)
+		operatorTarballPath, err = chartCache.Chart(ctx, operatorTarballURL, chart.Digest, helmClient.PullChartTarball)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
+		// The path is passed since operatorTarballPath is replaced by the
+		// namespaced copy below.
+		defer func(path string) {
+			err := os.Remove(path)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "deletion of %#q failed", path)
+			}
+		}(operatorTarballPath)
+
 		r.logger.Debugf(ctx, "tarball path is %#q", operatorTarballPath)
 	}
 
-	{
+	var namespacedValues map[string]interface{}
+	if r.cluster.Namespaced {
+		var namespacedTarballPath string
+		var err error
+		namespacedTarballPath, namespacedValues, err = namespacedChart(operatorTarballPath, namespace)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
 		defer func() {
-			fs := afero.NewOsFs()
-			err := fs.Remove(operatorTarballPath)
+			err := os.RemoveAll(filepath.Dir(namespacedTarballPath))
 			if err != nil {
-				r.logger.Errorf(ctx, err, "deletion of %#q failed", operatorTarballPath)
+				r.logger.Errorf(ctx, err, "deletion of %#q failed", namespacedTarballPath)
 			}
 		}()
 
-		r.logger.Debugf(ctx, "installing %#q", name)
+		operatorTarballPath = namespacedTarballPath
+	}
+
+	{
+		r.logger.Debugf(ctx, "installing %#q as release '%s/%s'", name, namespace, releaseName)
 
 		var input map[string]interface{}
 
@@ -720,57 +1037,76 @@ func (r *runner) installOperator(ctx context.Context, helmClient helmclient.Inte
 		if err != nil {
 			return microerror.Mask(err)
 		}
+		mergeValues(input, values)
+		mergeValues(input, namespacedValues)
 
 		opts := helmclient.InstallOptions{
-			ReleaseName: name,
+			ReleaseName: releaseName,
 		}
 		err = helmClient.InstallReleaseFromTarball(ctx,
 			operatorTarballPath,
 			namespace,
 			input,
 			opts)
-		if helmclient.IsCannotReuseRelease(err) {
-			r.logger.Debugf(ctx, "%#q already installed", name)
-			return nil
-		} else if helmclient.IsReleaseAlreadyExists(err) {
-			r.logger.Debugf(ctx, "%#q already installed", name)
+		if helmclient.IsCannotReuseRelease(err) || helmclient.IsReleaseAlreadyExists(err) {
+			// Releases installed by earlier versions are labelled anyway.
+			r.logger.Debugf(ctx, "%#q already installed", releaseName)
+			err = r.labelRelease(ctx, k8sClients, namespace, releaseName)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
 			return nil
 		} else if err != nil {
 			return microerror.Mask(err)
 		}
 
-		r.logger.Debugf(ctx, "installed %#q", name)
+		r.track(fmt.Sprintf("release %s/%s", namespace, releaseName), func(ctx context.Context) error {
+			err := helmClient.DeleteRelease(ctx, namespace, releaseName, helmclient.DeleteOptions{})
+			if helmclient.IsReleaseNotFound(err) {
+				return nil
+			} else if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		})
+
+		r.logger.Debugf(ctx, "installed %#q", releaseName)
+
+		err = r.labelRelease(ctx, k8sClients, namespace, releaseName)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
 	return nil
 }
 
 func (r *runner) waitForChartMuseum(ctx context.Context, appTest apptest.Interface) error {
-	var err error
-
 	deployName := chartMuseumName
 
 	r.logger.Debugf(ctx, "waiting for ready %#q deployment", deployName)
 
-	o := func() error {
-		deploy, err := appTest.K8sClient().AppsV1().Deployments(namespace).Get(ctx, deployName, metav1.GetOptions{})
+	deployments := appTest.K8sClient().AppsV1().Deployments(r.namespace())
+
+	ready := func(ctx context.Context) (bool, error) {
+		deploy, err := deployments.Get(ctx, deployName, metav1.GetOptions{})
 		if err != nil {
-			return microerror.Mask(err)
+			return false, microerror.Mask(err)
 		}
 
-		if *deploy.Spec.Replicas != deploy.Status.ReadyReplicas {
-			return microerror.Maskf(executionFailedError, "waiting for %d ready pods, current %d", *deploy.Spec.Replicas, deploy.Status.ReadyReplicas)
+		if replicas(deploy.Spec.Replicas) != deploy.Status.ReadyReplicas {
+			return false, microerror.Maskf(executionFailedError, "waiting for %d ready pods, current %d", replicas(deploy.Spec.Replicas), deploy.Status.ReadyReplicas)
 		}
 
-		return nil
+		return true, nil
 	}
-
-	n := func(err error, t time.Duration) {
-		r.logger.Errorf(ctx, err, "failed to get ready deployment '%s': retrying in %s", deployName, t)
+	watchDeployment := func(ctx context.Context) (watch.Interface, error) {
+		return deployments.Watch(ctx, await.NameSelector(deployName))
 	}
 
-	b := backoff.NewConstant(5*time.Minute, 10*time.Second)
-	err = backoff.RetryNotify(o, b, n)
+	err := await.For(ctx, r.logger, fmt.Sprintf("ready %s deployment", deployName), 5*time.Minute, await.FallbackInterval, ready, watchDeployment)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -779,3 +1115,45 @@ func (r *runner) waitForChartMuseum(ctx context.Context, appTest apptest.Interfa
 
 	return nil
 }
+
+// replicas returns the desired replicas of a deployment, defaulting to one
+// like the API server does.
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+
+	return *r
+}
+
+// namespace is the namespace of the app platform instance selected with
+// --instance.
+func (r *runner) namespace() string {
+	return key.Namespace(r.cluster.Instance)
+}
+
+// chartMuseumStorageURL is the URL app-operator and chart-operator pull
+// pushed charts from. Instances use the fully qualified service name since
+// their operators do not share the chartmuseum namespace with all clients.
+func (r *runner) chartMuseumStorageURL() string {
+	if r.cluster.Instance == "" {
+		return "http://chartmuseum:8080/"
+	}
+
+	return fmt.Sprintf("http://%s.%s:8080/", key.ChartMuseumName(), r.namespace())
+}
+
+// operatorValues scopes the operators of an instance or of a namespaced
+// bootstrap to the platform namespace. The default platform keeps watching
+// all namespaces.
+func (r *runner) operatorValues(name string) map[string]interface{} {
+	if (r.cluster.Instance == "" && !r.cluster.Namespaced) || name != "app-operator" {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"app": map[string]interface{}{
+			"watchNamespace": r.namespace(),
+		},
+	}
+}
@@ -0,0 +1,212 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/giantswarm/apptestctl/pkg/cache"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+// bootstrapWorkloadCluster prepares a second cluster for app-operator's
+// remote cluster mode. The CRDs and chart-operator are installed into the
+// workload cluster and its kubeconfig is stored in the management cluster,
+// where App CRs reference it via Spec.KubeConfig.
+func (r *runner) bootstrapWorkloadCluster(ctx context.Context, k8sClients k8sclient.Interface, chartCache *cache.Cache) error {
+	var err error
+
+	_, _ = fmt.Fprintf(r.stdout, "bootstrapping workload cluster %s\n", r.flag.WorkloadCluster)
+
+	var workloadCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag: &cluster.Flag{
+				KubeConfigPath: r.flag.WorkloadKubeConfig,
+			},
+		}
+		workloadCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var workloadClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger:     r.logger,
+			RestConfig: workloadCluster.RESTConfig(),
+		}
+		workloadClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = r.ensureCRDs(ctx, workloadClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.ensurePriorityClass(ctx, workloadClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.ensureNamespace(ctx, workloadClients, key.Namespace(""))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Workload clusters get the default chart-operator regardless of
+	// --instance since they are not shared between instances.
+	if r.flag.InstallOperators {
+		helmClient, err := r.newHelmClient(workloadClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = r.installOperator(ctx, workloadClients, helmClient, chartCache, "chart-operator", chartOperatorVersion, r.flag.ChartOperatorChart, key.ChartOperatorName(""), key.Namespace(""), nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// app-operator connects to the workload cluster from inside the
+	// management cluster, which may need a different API server address
+	// than apptestctl, e.g. for kind clusters.
+	internalCluster := workloadCluster
+	if r.flag.WorkloadKubeConfigInternal != "" {
+		c := cluster.Config{
+			Flag: &cluster.Flag{
+				KubeConfigPath: r.flag.WorkloadKubeConfigInternal,
+			},
+		}
+		internalCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	kubeConfig, err := renameContext(internalCluster.KubeConfig(), r.flag.WorkloadCluster)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	externalKubeConfig, err := renameContext(workloadCluster.KubeConfig(), r.flag.WorkloadCluster)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.ensureWorkloadClusterKubeConfig(ctx, k8sClients, kubeConfig, externalKubeConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "workload cluster %s is ready, install apps into it with apptestctl app install --workload-cluster %s\n", r.flag.WorkloadCluster, r.flag.WorkloadCluster)
+
+	return nil
+}
+
+// ensureWorkloadClusterKubeConfig stores the kubeconfig in the workload
+// cluster's namespace of the management cluster. Both the kubeConfig and
+// value keys are set since app-operator versions differ in which one they
+// read. The kubeconfig apptestctl itself connects with, e.g. in reset and
+// app test, is stored separately.
+func (r *runner) ensureWorkloadClusterKubeConfig(ctx context.Context, k8sClients k8sclient.Interface, kubeConfig, externalKubeConfig string) error {
+	clusterNamespace := key.WorkloadClusterNamespace(r.flag.WorkloadCluster)
+
+	r.logger.Debugf(ctx, "ensuring namespace %#q", clusterNamespace)
+
+	n := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterNamespace,
+		},
+	}
+	r.setMetadata(n)
+
+	_, err := k8sClients.K8sClient().CoreV1().Namespaces().Create(ctx, n, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		r.logger.Debugf(ctx, "namespace %#q already exists", clusterNamespace)
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		r.track(fmt.Sprintf("namespace %s", clusterNamespace), func(ctx context.Context) error {
+			return k8sClients.K8sClient().CoreV1().Namespaces().Delete(ctx, clusterNamespace, metav1.DeleteOptions{})
+		})
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.WorkloadClusterKubeConfigSecretName(r.flag.WorkloadCluster),
+			Namespace: clusterNamespace,
+			Labels: map[string]string{
+				label.Cluster: r.flag.WorkloadCluster,
+			},
+		},
+		Data: map[string][]byte{
+			"kubeConfig":                     []byte(kubeConfig),
+			"value":                          []byte(kubeConfig),
+			key.WorkloadClusterKubeConfigKey: []byte(externalKubeConfig),
+		},
+	}
+
+	r.logger.Debugf(ctx, "ensuring secret '%s/%s'", secret.Namespace, secret.Name)
+
+	secrets := k8sClients.K8sClient().CoreV1().Secrets(secret.Namespace)
+
+	r.setMetadata(secret)
+
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		r.track(fmt.Sprintf("secret %s/%s", secret.Namespace, secret.Name), func(ctx context.Context) error {
+			return secrets.Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		})
+	}
+
+	r.logger.Debugf(ctx, "ensured secret '%s/%s'", secret.Namespace, secret.Name)
+
+	return nil
+}
+
+// renameContext names the only context of the minified kubeconfig after the
+// workload cluster, which is the context App CRs select.
+func renameContext(kubeConfig, name string) (string, error) {
+	apiConfig, err := clientcmd.Load([]byte(kubeConfig))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	kubeContext, ok := apiConfig.Contexts[apiConfig.CurrentContext]
+	if !ok {
+		return "", microerror.Maskf(invalidConfigError, "current context %#q not found in workload cluster kubeconfig", apiConfig.CurrentContext)
+	}
+
+	apiConfig.Contexts = map[string]*clientcmdapi.Context{
+		name: kubeContext,
+	}
+	apiConfig.CurrentContext = name
+
+	bytes, err := clientcmd.Write(*apiConfig)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return string(bytes), nil
+}
@@ -0,0 +1,28 @@
+package list
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/cache"
+)
+
+const (
+	cacheDir = "cache-dir"
+)
+
+type flag struct {
+	CacheDir string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.CacheDir, cacheDir, cache.DefaultDir(), "Directory for caching catalog indexes and chart tarballs")
+}
+
+func (f *flag) Validate() error {
+	if f.CacheDir == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", cacheDir)
+	}
+
+	return nil
+}
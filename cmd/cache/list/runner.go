@@ -0,0 +1,81 @@
+package list
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/cache"
+)
+
+type runner struct {
+	flag   *flag
+	logger micrologger.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var chartCache *cache.Cache
+	{
+		c := cache.Config{
+			Logger: r.logger,
+
+			Dir: r.flag.CacheDir,
+		}
+		chartCache, err = cache.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	entries, err := chartCache.List()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	w := tabwriter.NewWriter(r.stdout, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KIND\tURL\tDIGEST\tSIZE\tLAST USED")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", e.Kind, e.URL, shortDigest(e.Digest), e.Size, e.LastUsed.Format(time.RFC3339))
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+
+	return digest
+}
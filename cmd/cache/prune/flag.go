@@ -0,0 +1,36 @@
+package prune
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/cache"
+)
+
+const (
+	cacheDir  = "cache-dir"
+	olderThan = "older-than"
+)
+
+type flag struct {
+	CacheDir  string
+	OlderThan time.Duration
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.CacheDir, cacheDir, cache.DefaultDir(), "Directory for caching catalog indexes and chart tarballs")
+	cmd.Flags().DurationVar(&f.OlderThan, olderThan, 0, "Only remove entries not used within this duration, e.g. 168h. By default all entries are removed.")
+}
+
+func (f *flag) Validate() error {
+	if f.CacheDir == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", cacheDir)
+	}
+	if f.OlderThan < 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must not be negative", olderThan)
+	}
+
+	return nil
+}
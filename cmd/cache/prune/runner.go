@@ -0,0 +1,65 @@
+package prune
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/cache"
+)
+
+type runner struct {
+	flag   *flag
+	logger micrologger.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var chartCache *cache.Cache
+	{
+		c := cache.Config{
+			Logger: r.logger,
+
+			Dir: r.flag.CacheDir,
+		}
+		chartCache, err = cache.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	pruned, err := chartCache.Prune(r.flag.OlderThan)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, e := range pruned {
+		_, _ = fmt.Fprintf(r.stdout, "removed %s %s\n", e.Kind, e.URL)
+	}
+	_, _ = fmt.Fprintf(r.stdout, "removed %d cache entries\n", len(pruned))
+
+	return nil
+}
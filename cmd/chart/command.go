@@ -0,0 +1,120 @@
+package chart
+
+import (
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/cmd/chart/delete"
+	"github.com/giantswarm/apptestctl/cmd/chart/list"
+	"github.com/giantswarm/apptestctl/cmd/chart/push"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+)
+
+const (
+	name        = "chart"
+	description = "Manages charts in the in-cluster chartmuseum."
+)
+
+type Config struct {
+	Cluster *cluster.Flag
+	Logger  micrologger.Logger
+	Stderr  io.Writer
+	Stdin   io.Reader
+	Stdout  io.Writer
+}
+
+func New(config Config) (*cobra.Command, error) {
+	if config.Cluster == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Cluster must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Stderr == nil {
+		config.Stderr = os.Stderr
+	}
+	if config.Stdin == nil {
+		config.Stdin = os.Stdin
+	}
+	if config.Stdout == nil {
+		config.Stdout = os.Stdout
+	}
+
+	var err error
+
+	var deleteCmd *cobra.Command
+	{
+		c := delete.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		deleteCmd, err = delete.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var listCmd *cobra.Command
+	{
+		c := list.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		listCmd, err = list.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var pushCmd *cobra.Command
+	{
+		c := push.Config{
+			Cluster: config.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		pushCmd, err = push.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	f := &flag{}
+
+	r := &runner{
+		flag:   f,
+		logger: config.Logger,
+		stderr: config.Stderr,
+		stdout: config.Stdout,
+	}
+
+	c := &cobra.Command{
+		Use:   name,
+		Short: description,
+		Long:  description,
+		RunE:  r.Run,
+	}
+
+	f.Init(c)
+
+	c.AddCommand(deleteCmd)
+	c.AddCommand(listCmd)
+	c.AddCommand(pushCmd)
+
+	return c, nil
+}
@@ -0,0 +1,154 @@
+package delete
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/chartmuseum"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	chartMuseumNamespace, chartMuseumService, err := chartmuseum.Locate(ctx, k8sClients.CtrlClient(), r.cluster.Instance, r.cluster.Namespaced)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var chartMuseum *chartmuseum.Client
+	{
+		c := chartmuseum.Config{
+			K8sClient:  k8sClients.K8sClient(),
+			Logger:     r.logger,
+			RestConfig: k8sClients.RESTConfig(),
+
+			Namespace: chartMuseumNamespace,
+			Service:   chartMuseumService,
+		}
+		chartMuseum, err = chartmuseum.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	name := args[0]
+	var version string
+	if len(args) > 1 {
+		version = args[1]
+	}
+
+	deleted, err := chartMuseum.Delete(ctx, name, version)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if r.flag.Output == outputJSON {
+		result := struct {
+			Name    string   `json:"name"`
+			Deleted []string `json:"deleted"`
+		}{
+			Name:    name,
+			Deleted: deleted,
+		}
+		if result.Deleted == nil {
+			result.Deleted = []string{}
+		}
+
+		e := json.NewEncoder(r.stdout)
+		e.SetIndent("", "  ")
+		err = e.Encode(result)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	for _, v := range deleted {
+		_, _ = fmt.Fprintf(r.stdout, "deleted chart %s-%s\n", name, v)
+	}
+
+	return nil
+}
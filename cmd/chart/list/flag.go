@@ -0,0 +1,47 @@
+package list
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+)
+
+const (
+	logLevel = "log-level"
+	output   = "output"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+type flag struct {
+	LogLevel string
+	Output   string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().StringVarP(&f.Output, output, "o", outputTable, "Output format. Either table or json.")
+}
+
+func (f *flag) Validate() error {
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if !containsString([]string{outputJSON, outputTable}, f.Output) {
+		return microerror.Maskf(invalidFlagError, "--%s must be either %s or %s", output, outputTable, outputJSON)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package push
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+)
+
+const (
+	logLevel = "log-level"
+	timeout  = "timeout"
+	wait     = "wait"
+)
+
+type flag struct {
+	LogLevel string
+	Timeout  time.Duration
+	Wait     bool
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().DurationVar(&f.Timeout, timeout, 5*time.Minute, "Maximum time to wait for the AppCatalogEntry when --wait is set")
+	cmd.Flags().BoolVarP(&f.Wait, wait, "w", false, "Wait for app-operator to create the AppCatalogEntry of the pushed chart")
+}
+
+func (f *flag) Validate() error {
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if f.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be positive", timeout)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
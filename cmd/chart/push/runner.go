@@ -0,0 +1,188 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/backoff"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/chartmuseum"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	tarball, chart, err := chartmuseum.PackageChart(args[0])
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	name := chart.Metadata.Name
+	version := chart.Metadata.Version
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	chartMuseumNamespace, chartMuseumService, err := chartmuseum.Locate(ctx, k8sClients.CtrlClient(), r.cluster.Instance, r.cluster.Namespaced)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var chartMuseum *chartmuseum.Client
+	{
+		c := chartmuseum.Config{
+			K8sClient:  k8sClients.K8sClient(),
+			Logger:     r.logger,
+			RestConfig: k8sClients.RESTConfig(),
+
+			Namespace: chartMuseumNamespace,
+			Service:   chartMuseumService,
+		}
+		chartMuseum, err = chartmuseum.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "pushing chart %s-%s\n", name, version)
+
+	err = chartMuseum.Push(ctx, tarball)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "pushed chart %s-%s\n", name, version)
+
+	if !r.flag.Wait {
+		return nil
+	}
+
+	err = r.waitForAppCatalogEntry(ctx, k8sClients, name, version)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "appcatalogentry for %s-%s is ready in catalog %s\n", name, version, key.ChartMuseumCatalogName(r.cluster.Instance))
+
+	return nil
+}
+
+// waitForAppCatalogEntry blocks until app-operator picked up the pushed chart
+// version from the chartmuseum index.
+func (r *runner) waitForAppCatalogEntry(ctx context.Context, k8sClients k8sclient.Interface, name, version string) error {
+	r.logger.Debugf(ctx, "waiting for appcatalogentry of %#q version %#q", name, version)
+
+	o := func() error {
+		var entries v1alpha1.AppCatalogEntryList
+		err := k8sClients.CtrlClient().List(ctx, &entries,
+			client.InNamespace(key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced)),
+			client.MatchingLabels{label.CatalogName: key.ChartMuseumCatalogName(r.cluster.Instance)},
+		)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		for _, entry := range entries.Items {
+			if entry.Spec.AppName == name && entry.Spec.Version == version {
+				return nil
+			}
+		}
+
+		return microerror.Maskf(executionFailedError, "no appcatalogentry for %#q version %#q yet", name, version)
+	}
+
+	n := func(err error, t time.Duration) {
+		r.logger.Debugf(ctx, "%s: retrying in %s", err, t)
+	}
+
+	b := backoff.NewConstant(r.flag.Timeout, 5*time.Second)
+	err := backoff.RetryNotify(o, b, n)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "waited for appcatalogentry of %#q version %#q", name, version)
+
+	return nil
+}
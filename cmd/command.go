@@ -8,7 +8,15 @@ import (
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/cobra"
 
+	"github.com/giantswarm/apptestctl/cmd/app"
 	"github.com/giantswarm/apptestctl/cmd/bootstrap"
+	"github.com/giantswarm/apptestctl/cmd/cache"
+	"github.com/giantswarm/apptestctl/cmd/chart"
+	"github.com/giantswarm/apptestctl/cmd/preflight"
+	"github.com/giantswarm/apptestctl/cmd/reset"
+	"github.com/giantswarm/apptestctl/cmd/run"
+	"github.com/giantswarm/apptestctl/cmd/status"
+	"github.com/giantswarm/apptestctl/cmd/teardown"
 	"github.com/giantswarm/apptestctl/cmd/version"
 	"github.com/giantswarm/apptestctl/pkg/project"
 )
@@ -16,6 +24,7 @@ import (
 type Config struct {
 	Logger micrologger.Logger
 	Stderr io.Writer
+	Stdin  io.Reader
 	Stdout io.Writer
 }
 
@@ -26,21 +35,156 @@ func New(config Config) (*cobra.Command, error) {
 	if config.Stderr == nil {
 		config.Stderr = os.Stderr
 	}
+	if config.Stdin == nil {
+		config.Stdin = os.Stdin
+	}
 	if config.Stdout == nil {
 		config.Stdout = os.Stdout
 	}
 
 	var err error
 
+	// The root flags are created upfront because the cluster connection flags
+	// are persistent and shared with the subcommands.
+	f := &flag{}
+
+	var appCmd *cobra.Command
+	{
+		c := app.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		appCmd, err = app.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var bootstrapCmd *cobra.Command
 	{
 		c := bootstrap.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		bootstrapCmd, err = bootstrap.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var cacheCmd *cobra.Command
+	{
+		c := cache.Config{
 			Logger: config.Logger,
 			Stderr: config.Stderr,
 			Stdout: config.Stdout,
 		}
 
-		bootstrapCmd, err = bootstrap.New(c)
+		cacheCmd, err = cache.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var chartCmd *cobra.Command
+	{
+		c := chart.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		chartCmd, err = chart.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var preflightCmd *cobra.Command
+	{
+		c := preflight.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		preflightCmd, err = preflight.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var resetCmd *cobra.Command
+	{
+		c := reset.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		resetCmd, err = reset.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var runCmd *cobra.Command
+	{
+		c := run.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		runCmd, err = run.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var statusCmd *cobra.Command
+	{
+		c := status.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		statusCmd, err = status.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var teardownCmd *cobra.Command
+	{
+		c := teardown.Config{
+			Cluster: &f.Cluster,
+			Logger:  config.Logger,
+			Stderr:  config.Stderr,
+			Stdin:   config.Stdin,
+			Stdout:  config.Stdout,
+		}
+
+		teardownCmd, err = teardown.New(c)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -60,8 +204,6 @@ func New(config Config) (*cobra.Command, error) {
 		}
 	}
 
-	f := &flag{}
-
 	r := &runner{
 		flag:   f,
 		logger: config.Logger,
@@ -79,7 +221,15 @@ func New(config Config) (*cobra.Command, error) {
 
 	f.Init(c)
 
+	c.AddCommand(appCmd)
 	c.AddCommand(bootstrapCmd)
+	c.AddCommand(cacheCmd)
+	c.AddCommand(chartCmd)
+	c.AddCommand(preflightCmd)
+	c.AddCommand(resetCmd)
+	c.AddCommand(runCmd)
+	c.AddCommand(statusCmd)
+	c.AddCommand(teardownCmd)
 	c.AddCommand(versionCmd)
 
 	return c, nil
@@ -1,11 +1,17 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+)
 
 type flag struct {
+	Cluster cluster.Flag
 }
 
 func (f *flag) Init(cmd *cobra.Command) {
+	f.Cluster.Init(cmd)
 }
 
 func (f *flag) Validate() error {
@@ -0,0 +1,30 @@
+package preflight
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidFlagError = &microerror.Error{
+	Kind: "invalidFlagError",
+}
+
+// IsInvalidFlag asserts invalidFlagError.
+func IsInvalidFlag(err error) bool {
+	return microerror.Cause(err) == invalidFlagError
+}
+
+var preflightFailedError = &microerror.Error{
+	Kind: "preflightFailedError",
+}
+
+// IsPreflightFailed asserts preflightFailedError.
+func IsPreflightFailed(err error) bool {
+	return microerror.Cause(err) == preflightFailedError
+}
@@ -0,0 +1,115 @@
+package preflight
+
+import (
+	"context"
+	"io"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/preflight"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClient kubernetes.Interface
+	{
+		k8sClient, err = kubernetes.NewForConfig(targetCluster.RESTConfig())
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var p *preflight.Preflight
+	{
+		c := preflight.Config{
+			K8sClient: k8sClient,
+			Logger:    r.logger,
+
+			AppCRNamespace: key.AppCRNamespace(r.cluster.Instance, r.cluster.Namespaced),
+			Namespace:      key.Namespace(r.cluster.Instance),
+			Namespaced:     r.cluster.Namespaced,
+		}
+		p, err = preflight.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	results, err := p.Run(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	results.Print(r.stdout)
+
+	failed := results.Failed()
+	if len(failed) > 0 {
+		return microerror.Maskf(preflightFailedError, "%d preflight checks failed", len(failed))
+	}
+
+	return nil
+}
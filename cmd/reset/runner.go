@@ -0,0 +1,539 @@
+package reset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/await"
+	"github.com/giantswarm/apptestctl/pkg/chartmuseum"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/lock"
+)
+
+// release identifies a Helm release chart-operator created for a Chart CR.
+// K8sClient is the client of the cluster the release lives in.
+type release struct {
+	Name      string
+	Namespace string
+	K8sClient kubernetes.Interface
+}
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+
+	// workloads caches the clients of workload clusters by kubeconfig
+	// Secret and context.
+	workloads map[string]k8sclient.Interface
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) (err error) {
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var ctrlClient client.WithWatch
+	{
+		c := client.Options{
+			Scheme: k8sClients.Scheme(),
+		}
+		ctrlClient, err = client.NewWithWatch(k8sClients.RESTConfig(), c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var waiter *appwait.Waiter
+	{
+		c := appwait.Config{
+			Client: ctrlClient,
+			Logger: r.logger,
+
+			ChartNamespace: key.Namespace(r.cluster.Instance),
+		}
+		waiter, err = appwait.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var platformLock *lock.Lock
+	{
+		c := lock.Config{
+			K8sClient: k8sClients.K8sClient(),
+			Logger:    r.logger,
+			Stdout:    r.stdout,
+
+			Annotations: key.Annotations(),
+			Holder:      fmt.Sprintf("reset on %s", lock.Identity()),
+			Labels:      key.Labels(r.cluster.Instance),
+			Name:        key.LockName(r.cluster.Instance),
+			Namespace:   key.LockNamespace(r.cluster.Instance, r.cluster.Namespaced),
+		}
+		platformLock, err = lock.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	ctx, err = platformLock.Acquire(ctx, r.flag.LockTimeout)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer func() {
+		err := platformLock.Release()
+		if err != nil {
+			r.logger.Errorf(context.Background(), err, "failed to release lock")
+		}
+	}()
+	// Losing the lock cancels ctx, which makes the running step fail with a
+	// context error. The cause tells users why.
+	defer func() {
+		if err != nil && lock.IsLockLost(context.Cause(ctx)) {
+			err = microerror.Mask(context.Cause(ctx))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, r.flag.Timeout)
+	defer cancel()
+
+	apps, err := r.listApps(ctx, k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The releases are looked up before anything is deleted because the
+	// Chart CRs naming them are gone afterwards.
+	var deleted []v1alpha1.App
+	var releases []release
+	for _, app := range apps {
+		rel, err := r.release(ctx, k8sClients, app)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if rel != nil {
+			releases = append(releases, *rel)
+		}
+
+		err = r.deleteApp(ctx, k8sClients, app)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		deleted = append(deleted, app)
+	}
+
+	// Chart CRs without an App CR are leftovers of apps whose deletion did
+	// not finish or of charts created directly. Chart CRs of App CRs kept
+	// above, e.g. the platform's or ones not created by apptestctl, are not
+	// orphaned.
+	kept, err := r.keptApps(ctx, k8sClients, deleted)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var charts v1alpha1.ChartList
+	err = k8sClients.CtrlClient().List(ctx, &charts, client.InNamespace(key.Namespace(r.cluster.Instance)))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var orphans []v1alpha1.Chart
+	for i := range charts.Items {
+		chart := &charts.Items[i]
+
+		if kept[chart.Name] || hasApp(deleted, chart.Name) {
+			continue
+		}
+
+		releases = append(releases, release{Name: chart.Spec.Name, Namespace: chart.Spec.Namespace, K8sClient: k8sClients.K8sClient()})
+
+		_, _ = fmt.Fprintf(r.stdout, "deleting orphaned chart %s/%s\n", chart.Namespace, chart.Name)
+
+		err = k8sClients.CtrlClient().Delete(ctx, chart)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+
+		orphans = append(orphans, *chart)
+	}
+
+	for _, app := range deleted {
+		r.logger.Debugf(ctx, "waiting for app '%s/%s' to be deleted", app.Namespace, app.Name)
+
+		err = waiter.Wait(ctx, app.Namespace, app.Name, appwait.Condition{Status: appwait.DeletedStatus})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, chart := range orphans {
+		err = r.waitForChartDeleted(ctx, ctrlClient, chart)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, rel := range releases {
+		err = r.waitForReleaseDeleted(ctx, rel)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if r.flag.DeleteCharts {
+		err = r.deleteCharts(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "reset app platform, deleted %d apps and %d orphaned charts\n", len(deleted), len(orphans))
+
+	return nil
+}
+
+// listApps returns the App CRs apptestctl created for tests against the
+// selected instance, e.g. with app install, selected by their labels. App CRs
+// of the platform itself and App CRs not created by apptestctl are never
+// returned. Namespaced platforms only own their namespace.
+func (r *runner) listApps(ctx context.Context, k8sClients k8sclient.Interface) ([]v1alpha1.App, error) {
+	opts := []client.ListOption{
+		client.MatchingLabelsSelector{Selector: key.TestSelector(r.cluster.Instance)},
+	}
+	if r.cluster.Namespaced {
+		opts = append(opts, client.InNamespace(key.Namespace(r.cluster.Instance)))
+	}
+
+	var apps v1alpha1.AppList
+	err := k8sClients.CtrlClient().List(ctx, &apps, opts...)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return apps.Items, nil
+}
+
+// keptApps returns the names of the App CRs this run did not delete. Their
+// Chart CRs share their name.
+func (r *runner) keptApps(ctx context.Context, k8sClients k8sclient.Interface, deleted []v1alpha1.App) (map[string]bool, error) {
+	var opts []client.ListOption
+	if r.cluster.Namespaced {
+		opts = append(opts, client.InNamespace(key.Namespace(r.cluster.Instance)))
+	}
+
+	var apps v1alpha1.AppList
+	err := k8sClients.CtrlClient().List(ctx, &apps, opts...)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	names := map[string]bool{}
+	for _, app := range apps.Items {
+		if hasApp(deleted, app.Name) {
+			continue
+		}
+		names[app.Name] = true
+	}
+
+	return names, nil
+}
+
+// release returns the Helm release of the Chart CR app-operator created for
+// the App, nil when there is none yet. App CRs of workload clusters have
+// their Chart CR and release in the cluster their kubeconfig Secret points
+// to, managed by the default chart-operator there.
+func (r *runner) release(ctx context.Context, k8sClients k8sclient.Interface, app v1alpha1.App) (*release, error) {
+	chartNamespace := key.Namespace(r.cluster.Instance)
+	if !app.Spec.KubeConfig.InCluster {
+		var err error
+		k8sClients, err = r.workloadClients(ctx, k8sClients, app)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		chartNamespace = key.Namespace("")
+	}
+
+	var chart v1alpha1.Chart
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: chartNamespace, Name: app.Name}, &chart)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	rel := &release{
+		Name:      chart.Spec.Name,
+		Namespace: chart.Spec.Namespace,
+		K8sClient: k8sClients.K8sClient(),
+	}
+
+	return rel, nil
+}
+
+// workloadClients returns the clients of the workload cluster an App CR
+// deploys into. Clients are reused for App CRs referencing the same
+// kubeconfig Secret.
+func (r *runner) workloadClients(ctx context.Context, k8sClients k8sclient.Interface, app v1alpha1.App) (k8sclient.Interface, error) {
+	secret := app.Spec.KubeConfig.Secret
+	id := fmt.Sprintf("%s/%s/%s", secret.Namespace, secret.Name, app.Spec.KubeConfig.Context.Name)
+
+	if clients, ok := r.workloads[id]; ok {
+		return clients, nil
+	}
+
+	workloadCluster, err := cluster.FromKubeConfigSecret(ctx, k8sClients.K8sClient(), secret.Namespace, secret.Name, app.Spec.KubeConfig.Context.Name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var workloadClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: workloadCluster.RESTConfig(),
+		}
+		workloadClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if r.workloads == nil {
+		r.workloads = map[string]k8sclient.Interface{}
+	}
+	r.workloads[id] = workloadClients
+
+	return workloadClients, nil
+}
+
+// deleteApp deletes the App CR along with the user values ConfigMap and
+// Secret apptestctl and the apptest library create for it. User config with
+// other names may be shared and is left alone.
+func (r *runner) deleteApp(ctx context.Context, k8sClients k8sclient.Interface, app v1alpha1.App) error {
+	_, _ = fmt.Fprintf(r.stdout, "deleting app %s/%s\n", app.Namespace, app.Name)
+
+	err := k8sClients.CtrlClient().Delete(ctx, &app)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	configMap := app.Spec.UserConfig.ConfigMap
+	if configMap.Name == key.UserValuesName(app.Name) {
+		err = k8sClients.K8sClient().CoreV1().ConfigMaps(configMap.Namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	secret := app.Spec.UserConfig.Secret
+	if secret.Name == key.UserSecretName(app.Name) {
+		err = k8sClients.K8sClient().CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) deleteCharts(ctx context.Context, k8sClients k8sclient.Interface) error {
+	chartMuseumNamespace, chartMuseumService, err := chartmuseum.Locate(ctx, k8sClients.CtrlClient(), r.cluster.Instance, r.cluster.Namespaced)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var chartMuseum *chartmuseum.Client
+	{
+		c := chartmuseum.Config{
+			K8sClient:  k8sClients.K8sClient(),
+			Logger:     r.logger,
+			RestConfig: k8sClients.RESTConfig(),
+
+			Namespace: chartMuseumNamespace,
+			Service:   chartMuseumService,
+		}
+		chartMuseum, err = chartmuseum.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	versions, err := chartMuseum.List(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	names := map[string]bool{}
+	for _, v := range versions {
+		if names[v.Name] {
+			continue
+		}
+		names[v.Name] = true
+
+		_, _ = fmt.Fprintf(r.stdout, "deleting chart %s\n", v.Name)
+
+		_, err = chartMuseum.Delete(ctx, v.Name, "")
+		if chartmuseum.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// waitForChartDeleted waits until chart-operator removed the finalizers of
+// the orphaned Chart CR and it is gone.
+func (r *runner) waitForChartDeleted(ctx context.Context, ctrlClient client.WithWatch, chart v1alpha1.Chart) error {
+	deleted := func(ctx context.Context) (bool, error) {
+		err := ctrlClient.Get(ctx, client.ObjectKeyFromObject(&chart), &v1alpha1.Chart{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		return false, nil
+	}
+
+	watchChart := func(ctx context.Context) (watch.Interface, error) {
+		return ctrlClient.Watch(ctx, &v1alpha1.ChartList{},
+			client.InNamespace(chart.Namespace),
+			client.MatchingFields{"metadata.name": chart.Name},
+		)
+	}
+
+	err := await.For(ctx, r.logger, fmt.Sprintf("chart '%s/%s' to be removed", chart.Namespace, chart.Name), r.flag.Timeout, await.FallbackInterval, deleted, watchChart)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// waitForReleaseDeleted waits until chart-operator uninstalled the release,
+// i.e. Helm removed all Secrets storing its revisions.
+func (r *runner) waitForReleaseDeleted(ctx context.Context, rel release) error {
+	options := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s", rel.Name),
+	}
+
+	deleted := func(ctx context.Context) (bool, error) {
+		secrets, err := rel.K8sClient.CoreV1().Secrets(rel.Namespace).List(ctx, options)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		return len(secrets.Items) == 0, nil
+	}
+
+	watchRelease := func(ctx context.Context) (watch.Interface, error) {
+		return rel.K8sClient.CoreV1().Secrets(rel.Namespace).Watch(ctx, options)
+	}
+
+	err := await.For(ctx, r.logger, fmt.Sprintf("release '%s/%s' to be removed", rel.Namespace, rel.Name), r.flag.Timeout, await.FallbackInterval, deleted, watchRelease)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// hasApp returns whether apps contains an App CR with the given name.
+func hasApp(apps []v1alpha1.App, name string) bool {
+	for _, app := range apps {
+		if app.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package run
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+)
+
+const (
+	junit    = "junit"
+	logLevel = "log-level"
+	output   = "output"
+	timeout  = "timeout"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+type flag struct {
+	JUnit    string
+	LogLevel string
+	Output   string
+	Timeout  time.Duration
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.JUnit, junit, "", "Path of a JUnit XML report to write, e.g. for CI test result views")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().StringVarP(&f.Output, output, "o", outputTable, "Output format of the report. Either table or json.")
+	cmd.Flags().DurationVar(&f.Timeout, timeout, 5*time.Minute, "Timeout of steps that neither the step nor the scenario sets one for")
+}
+
+func (f *flag) Validate() error {
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if !containsString([]string{outputJSON, outputTable}, f.Output) {
+		return microerror.Maskf(invalidFlagError, "--%s must be either %s or %s", output, outputTable, outputJSON)
+	}
+	if f.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be greater than 0", timeout)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
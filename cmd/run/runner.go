@@ -0,0 +1,254 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/apptest"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/chartmuseum"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/scenario"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	// The scenario is read before connecting to the cluster so mistakes in
+	// the file are reported right away.
+	s, err := scenario.Read(args[0])
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var appTest apptest.Interface
+	{
+		c := apptest.Config{
+			KubeConfig: targetCluster.KubeConfig(),
+			Logger:     r.logger,
+		}
+		appTest, err = apptest.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	chartMuseumNamespace, chartMuseumService, err := chartmuseum.Locate(ctx, k8sClients.CtrlClient(), r.cluster.Instance, r.cluster.Namespaced)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var chartMuseum *chartmuseum.Client
+	{
+		c := chartmuseum.Config{
+			K8sClient:  k8sClients.K8sClient(),
+			Logger:     r.logger,
+			RestConfig: k8sClients.RESTConfig(),
+
+			Namespace: chartMuseumNamespace,
+			Service:   chartMuseumService,
+		}
+		chartMuseum, err = chartmuseum.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var ctrlClient client.WithWatch
+	{
+		c := client.Options{
+			Scheme: k8sClients.Scheme(),
+		}
+		ctrlClient, err = client.NewWithWatch(k8sClients.RESTConfig(), c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var waiter *appwait.Waiter
+	{
+		c := appwait.Config{
+			Client: ctrlClient,
+			Logger: r.logger,
+			Stdout: r.stderr,
+
+			ChartNamespace: key.Namespace(r.cluster.Instance),
+		}
+		waiter, err = appwait.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var scenarioRunner *scenario.Runner
+	{
+		c := scenario.Config{
+			AppTest:     appTest,
+			ChartMuseum: chartMuseum,
+			K8sClients:  k8sClients,
+			Logger:      r.logger,
+			Stdout:      r.stderr,
+			Waiter:      waiter,
+
+			Instance:   r.cluster.Instance,
+			Namespaced: r.cluster.Namespaced,
+			Timeout:    r.flag.Timeout,
+		}
+		scenarioRunner, err = scenario.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	rep := scenarioRunner.Run(ctx, s)
+
+	if r.flag.Output == outputJSON {
+		err = rep.WriteJSON(r.stdout)
+	} else {
+		err = r.printTable(rep)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if r.flag.JUnit != "" {
+		err = r.writeJUnit(rep)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if !rep.Passed {
+		return microerror.Maskf(scenarioFailedError, "scenario %#q failed", rep.Name)
+	}
+
+	return nil
+}
+
+func (r *runner) printTable(rep scenario.Report) error {
+	w := tabwriter.NewWriter(r.stdout, 0, 8, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "STEP\tKIND\tRESULT\tDURATION\tMESSAGE")
+	for _, s := range rep.Steps {
+		result := "passed"
+		if s.Skipped {
+			result = "skipped"
+		} else if !s.Passed {
+			result = "failed"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Kind, result, s.Duration.Round(time.Second), s.Message)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) writeJUnit(rep scenario.Report) error {
+	f, err := os.Create(r.flag.JUnit)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer f.Close()
+
+	err = rep.WriteJUnit(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
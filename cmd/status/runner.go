@@ -0,0 +1,355 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+const (
+	kindApp     = "app"
+	kindCatalog = "catalog"
+	kindRelease = "release"
+
+	deployedStatus = "deployed"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// platform is the status of an app platform instance.
+type platform struct {
+	Instance   string            `json:"instance"`
+	Namespace  string            `json:"namespace"`
+	Namespaced bool              `json:"namespaced"`
+	Healthy    bool              `json:"healthy"`
+	Info       map[string]string `json:"info"`
+	Components []component       `json:"components"`
+}
+
+// component is a release, App or Catalog CR bootstrap created.
+type component struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Status  string `json:"status"`
+	Healthy bool   `json:"healthy"`
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	p := platform{
+		Instance:   r.cluster.Instance,
+		Namespace:  key.Namespace(r.cluster.Instance),
+		Namespaced: r.cluster.Namespaced,
+		Healthy:    true,
+	}
+
+	configMap, err := k8sClients.K8sClient().CoreV1().ConfigMaps(p.Namespace).Get(ctx, key.InfoConfigMapName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "configmap '%s/%s' not found", p.Namespace, key.InfoConfigMapName())
+		p.Healthy = false
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		p.Info = configMap.Data
+	}
+
+	for _, releaseName := range []string{key.AppOperatorName(r.cluster.Instance), key.ChartOperatorName(r.cluster.Instance)} {
+		c, err := r.releaseStatus(ctx, k8sClients, releaseName, p.Namespace)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.Components = append(p.Components, c)
+	}
+
+	{
+		c, err := r.chartMuseumStatus(ctx, k8sClients, p.Namespace)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.Components = append(p.Components, c)
+	}
+
+	{
+		c, err := r.catalogStatus(ctx, k8sClients, key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced), key.ChartMuseumCatalogName(r.cluster.Instance))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.Components = append(p.Components, c)
+	}
+
+	for _, c := range p.Components {
+		if !c.Healthy {
+			p.Healthy = false
+		}
+	}
+
+	if r.flag.Output == outputJSON {
+		e := json.NewEncoder(r.stdout)
+		e.SetIndent("", "  ")
+		err = e.Encode(p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else {
+		err = printText(r.stdout, p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if !p.Healthy {
+		return microerror.Maskf(unhealthyError, "app platform in namespace %#q is not healthy", p.Namespace)
+	}
+
+	return nil
+}
+
+// releaseStatus reports the latest revision of an operator release and
+// whether all its workloads have their pods available.
+func (r *runner) releaseStatus(ctx context.Context, k8sClients k8sclient.Interface, name, namespace string) (component, error) {
+	c := component{
+		Kind: kindRelease,
+		Name: name,
+	}
+
+	latest, err := workload.LatestRelease(ctx, k8sClients.K8sClient(), name, namespace)
+	if workload.IsReleaseNotFound(err) {
+		c.Status = "not found"
+		return c, nil
+	} else if err != nil {
+		return component{}, microerror.Mask(err)
+	}
+
+	if latest.Chart != nil && latest.Chart.Metadata != nil {
+		c.Version = latest.Chart.Metadata.Version
+	}
+	c.Status = latest.Info.Status.String()
+	if c.Status != deployedStatus {
+		return c, nil
+	}
+
+	workloads, err := workload.FromManifest(latest.Manifest, namespace)
+	if err != nil {
+		return component{}, microerror.Mask(err)
+	}
+
+	c.Healthy = true
+	for _, w := range workloads {
+		available, desired, err := workload.Availability(ctx, k8sClients.K8sClient(), w)
+		if apierrors.IsNotFound(err) {
+			c.Healthy = false
+			c.Status = fmt.Sprintf("%s, %s not found", c.Status, w)
+			continue
+		} else if err != nil {
+			return component{}, microerror.Mask(err)
+		}
+
+		if available < desired {
+			c.Healthy = false
+		}
+		c.Status = fmt.Sprintf("%s, %d/%d pods of %s available", c.Status, available, desired, w.Name)
+	}
+
+	return c, nil
+}
+
+// chartMuseumStatus reports the chartmuseum App CR and whether its
+// Deployment in namespace is ready.
+func (r *runner) chartMuseumStatus(ctx context.Context, k8sClients k8sclient.Interface, namespace string) (component, error) {
+	c := component{
+		Kind: kindApp,
+		Name: key.ChartMuseumName(),
+	}
+
+	var app v1alpha1.App
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: key.AppCRNamespace(r.cluster.Instance, r.cluster.Namespaced), Name: key.ChartMuseumName()}, &app)
+	if apierrors.IsNotFound(err) {
+		c.Status = "not found"
+		return c, nil
+	} else if err != nil {
+		return component{}, microerror.Mask(err)
+	}
+
+	c.Version = app.Spec.Version
+	c.Status = app.Status.Release.Status
+	if c.Status == "" {
+		c.Status = "unknown"
+	}
+	if c.Status != deployedStatus {
+		return c, nil
+	}
+
+	deploy, err := k8sClients.K8sClient().AppsV1().Deployments(namespace).Get(ctx, key.ChartMuseumName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		c.Status = fmt.Sprintf("%s, deployment not found", c.Status)
+		return c, nil
+	} else if err != nil {
+		return component{}, microerror.Mask(err)
+	}
+
+	desired := int32(1)
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
+	}
+
+	c.Healthy = deploy.Status.ReadyReplicas >= desired
+	c.Status = fmt.Sprintf("%s, %d/%d pods ready", c.Status, deploy.Status.ReadyReplicas, desired)
+
+	return c, nil
+}
+
+// catalogStatus reports whether the Catalog CR exists.
+func (r *runner) catalogStatus(ctx context.Context, k8sClients k8sclient.Interface, namespace, name string) (component, error) {
+	c := component{
+		Kind: kindCatalog,
+		Name: fmt.Sprintf("%s/%s", namespace, name),
+	}
+
+	err := k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &v1alpha1.Catalog{})
+	if apierrors.IsNotFound(err) {
+		c.Status = "not found"
+		return c, nil
+	} else if err != nil {
+		return component{}, microerror.Mask(err)
+	}
+
+	c.Status = "found"
+	c.Healthy = true
+
+	return c, nil
+}
+
+func printText(out io.Writer, p platform) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	instance := p.Instance
+	if instance == "" {
+		instance = "<default>"
+	}
+
+	_, _ = fmt.Fprintf(w, "Instance:\t%s\n", instance)
+	_, _ = fmt.Fprintf(w, "Namespace:\t%s\n", p.Namespace)
+	_, _ = fmt.Fprintf(w, "Namespaced:\t%t\n", p.Namespaced)
+	_, _ = fmt.Fprintf(w, "Healthy:\t%t\n", p.Healthy)
+
+	if p.Info == nil {
+		_, _ = fmt.Fprintf(w, "Info:\t%s not found, not bootstrapped\n", key.InfoConfigMapName())
+	} else {
+		keys := make([]string, 0, len(p.Info))
+		for k := range p.Info {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		_, _ = fmt.Fprintln(w, "Info:")
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(w, "  %s:\t%s\n", k, p.Info[k])
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+
+	_, _ = fmt.Fprintln(w, "KIND\tNAME\tVERSION\tHEALTHY\tSTATUS")
+	for _, c := range p.Components {
+		version := c.Version
+		if version == "" {
+			version = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", c.Kind, c.Name, version, c.Healthy, c.Status)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
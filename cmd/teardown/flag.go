@@ -0,0 +1,50 @@
+package teardown
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+)
+
+const (
+	lockTimeout = "lock-timeout"
+	logLevel    = "log-level"
+	timeout     = "timeout"
+)
+
+type flag struct {
+	LockTimeout time.Duration
+	LogLevel    string
+	Timeout     time.Duration
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&f.LockTimeout, lockTimeout, 10*time.Minute, "Maximum time to wait for another bootstrap, reset or teardown of the platform to finish")
+	cmd.Flags().StringVarP(&f.LogLevel, logLevel, "l", "error", "Log level to be used for debug logging. Either debug, info, warning or error.")
+	cmd.Flags().DurationVar(&f.Timeout, timeout, 10*time.Minute, "Maximum time to wait for the apps and their releases to be removed")
+}
+
+func (f *flag) Validate() error {
+	if f.LockTimeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be greater than 0", lockTimeout)
+	}
+	if !containsString([]string{"", "debug", "info", "warning", "error"}, f.LogLevel) {
+		return microerror.Maskf(invalidFlagError, "Log level must be either debug, info, warning or error.")
+	}
+	if f.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagError, "--%s must be greater than 0", timeout)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
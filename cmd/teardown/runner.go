@@ -0,0 +1,436 @@
+package teardown
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"oras.land/oras-go/pkg/content"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/cluster"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/lock"
+)
+
+type runner struct {
+	cluster *cluster.Flag
+	flag    *flag
+	logger  micrologger.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	err := r.cluster.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.flag.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) (err error) {
+	var logger micrologger.Logger
+	{
+		c := micrologger.ActivationLoggerConfig{
+			Underlying: r.logger,
+
+			Activations: map[string]interface{}{
+				micrologger.KeyLevel: r.flag.LogLevel,
+			},
+		}
+		logger, err = micrologger.NewActivation(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.logger = logger
+	}
+
+	var targetCluster *cluster.Cluster
+	{
+		c := cluster.Config{
+			Flag:  r.cluster,
+			Stdin: r.stdin,
+		}
+		targetCluster, err = cluster.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var k8sClients k8sclient.Interface
+	{
+		c := k8sclient.ClientsConfig{
+			Logger: r.logger,
+			SchemeBuilder: k8sclient.SchemeBuilder{
+				v1alpha1.AddToScheme,
+			},
+			RestConfig: targetCluster.RESTConfig(),
+		}
+		k8sClients, err = k8sclient.NewClients(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var ctrlClient client.WithWatch
+	{
+		c := client.Options{
+			Scheme: k8sClients.Scheme(),
+		}
+		ctrlClient, err = client.NewWithWatch(k8sClients.RESTConfig(), c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var helmClient helmclient.Interface
+	{
+		c := helmclient.Config{
+			K8sClient:       k8sClients.K8sClient(),
+			Logger:          r.logger,
+			RegistryOptions: &content.RegistryOptions{},
+			RestClient:      k8sClients.RESTClient(),
+			RestConfig:      k8sClients.RESTConfig(),
+		}
+		helmClient, err = helmclient.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var waiter *appwait.Waiter
+	{
+		c := appwait.Config{
+			Client: ctrlClient,
+			Logger: r.logger,
+
+			ChartNamespace: r.namespace(),
+		}
+		waiter, err = appwait.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var platformLock *lock.Lock
+	{
+		c := lock.Config{
+			K8sClient: k8sClients.K8sClient(),
+			Logger:    r.logger,
+			Stdout:    r.stdout,
+
+			Annotations: key.Annotations(),
+			Holder:      fmt.Sprintf("teardown on %s", lock.Identity()),
+			Labels:      key.Labels(r.cluster.Instance),
+			Name:        key.LockName(r.cluster.Instance),
+			Namespace:   key.LockNamespace(r.cluster.Instance, r.cluster.Namespaced),
+		}
+		platformLock, err = lock.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	ctx, err = platformLock.Acquire(ctx, r.flag.LockTimeout)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer func() {
+		err := platformLock.Release()
+		if err != nil {
+			r.logger.Errorf(context.Background(), err, "failed to release lock")
+		}
+	}()
+	// Losing the lock cancels ctx, which makes the running step fail with a
+	// context error. The cause tells users why.
+	defer func() {
+		if err != nil && lock.IsLockLost(context.Cause(ctx)) {
+			err = microerror.Mask(context.Cause(ctx))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, r.flag.Timeout)
+	defer cancel()
+
+	// App CRs are deleted while the operators still run, so chart-operator
+	// uninstalls their releases and app-operator removes its finalizers.
+	apps, err := r.listApps(ctx, k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, app := range apps {
+		_, _ = fmt.Fprintf(r.stdout, "deleting app %s/%s\n", app.Namespace, app.Name)
+
+		err = r.deleteApp(ctx, k8sClients, app)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, app := range apps {
+		err = waiter.Wait(ctx, app.Namespace, app.Name, appwait.Condition{Status: appwait.DeletedStatus})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, releaseName := range []string{key.AppOperatorName(r.cluster.Instance), key.ChartOperatorName(r.cluster.Instance)} {
+		_, _ = fmt.Fprintf(r.stdout, "deleting release %s/%s\n", r.namespace(), releaseName)
+
+		err = helmClient.DeleteRelease(ctx, r.namespace(), releaseName, helmclient.DeleteOptions{})
+		if helmclient.IsReleaseNotFound(err) {
+			r.logger.Debugf(ctx, "release '%s/%s' not found", r.namespace(), releaseName)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = r.deleteCatalogs(ctx, k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Namespaced platforms cannot list cluster scoped resources and did not
+	// create any.
+	if !r.cluster.Namespaced {
+		err = r.deleteClusterRBAC(ctx, k8sClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = r.deleteNamespace(ctx, k8sClients)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, _ = fmt.Fprintf(r.stdout, "tore down app platform in namespace %s, deleted %d apps\n", r.namespace(), len(apps))
+
+	return nil
+}
+
+// listApps returns the App CRs in the platform namespace along with the App
+// CRs labelled for the instance elsewhere, e.g. of workload clusters.
+func (r *runner) listApps(ctx context.Context, k8sClients k8sclient.Interface) ([]v1alpha1.App, error) {
+	var inNamespace v1alpha1.AppList
+	err := k8sClients.CtrlClient().List(ctx, &inNamespace, client.InNamespace(r.namespace()))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// Namespaced platforms only own their namespace.
+	var labelled v1alpha1.AppList
+	if !r.cluster.Namespaced {
+		err = k8sClients.CtrlClient().List(ctx, &labelled, client.MatchingLabelsSelector{Selector: key.Selector(r.cluster.Instance)})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	seen := map[client.ObjectKey]bool{}
+
+	var apps []v1alpha1.App
+	for _, app := range append(inNamespace.Items, labelled.Items...) {
+		if seen[client.ObjectKeyFromObject(&app)] {
+			continue
+		}
+		seen[client.ObjectKeyFromObject(&app)] = true
+
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// deleteApp deletes the App CR along with the user values ConfigMap and
+// Secret apptestctl and the apptest library create for it, like reset.
+func (r *runner) deleteApp(ctx context.Context, k8sClients k8sclient.Interface, app v1alpha1.App) error {
+	err := k8sClients.CtrlClient().Delete(ctx, &app)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	configMap := app.Spec.UserConfig.ConfigMap
+	if configMap.Name == key.UserValuesName(app.Name) {
+		err = k8sClients.K8sClient().CoreV1().ConfigMaps(configMap.Namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	secret := app.Spec.UserConfig.Secret
+	if secret.Name == key.UserSecretName(app.Name) {
+		err = k8sClients.K8sClient().CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// deleteCatalogs deletes the Catalog CRs labelled for the instance.
+// Catalogs still used by other App CRs, e.g. ones installed outside
+// apptestctl, are kept.
+func (r *runner) deleteCatalogs(ctx context.Context, k8sClients k8sclient.Interface) error {
+	catalogNamespace := key.CatalogNamespace(r.cluster.Instance, r.cluster.Namespaced)
+
+	var catalogs v1alpha1.CatalogList
+	err := k8sClients.CtrlClient().List(ctx, &catalogs,
+		client.InNamespace(catalogNamespace),
+		client.MatchingLabelsSelector{Selector: key.Selector(r.cluster.Instance)},
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var apps v1alpha1.AppList
+	if r.cluster.Namespaced {
+		err = k8sClients.CtrlClient().List(ctx, &apps, client.InNamespace(r.namespace()))
+	} else {
+		err = k8sClients.CtrlClient().List(ctx, &apps)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	used := map[string]string{}
+	for _, app := range apps.Items {
+		namespace := app.Spec.CatalogNamespace
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		used[fmt.Sprintf("%s/%s", namespace, app.Spec.Catalog)] = fmt.Sprintf("%s/%s", app.Namespace, app.Name)
+	}
+
+	for i := range catalogs.Items {
+		catalog := &catalogs.Items[i]
+		name := fmt.Sprintf("%s/%s", catalog.Namespace, catalog.Name)
+
+		if app, ok := used[name]; ok {
+			_, _ = fmt.Fprintf(r.stdout, "keeping catalog %s, it is used by app %s\n", name, app)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(r.stdout, "deleting catalog %s\n", name)
+
+		err = k8sClients.CtrlClient().Delete(ctx, catalog)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// deleteClusterRBAC deletes the cluster scoped RBAC bootstrap created for
+// chartmuseum. The RBAC of the operators is removed with their releases.
+func (r *runner) deleteClusterRBAC(ctx context.Context, k8sClients k8sclient.Interface) error {
+	options := metav1.ListOptions{LabelSelector: key.Selector(r.cluster.Instance).String()}
+
+	bindings, err := k8sClients.K8sClient().RbacV1().ClusterRoleBindings().List(ctx, options)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, binding := range bindings.Items {
+		_, _ = fmt.Fprintf(r.stdout, "deleting clusterrolebinding %s\n", binding.Name)
+
+		err = k8sClients.K8sClient().RbacV1().ClusterRoleBindings().Delete(ctx, binding.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	roles, err := k8sClients.K8sClient().RbacV1().ClusterRoles().List(ctx, options)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, role := range roles.Items {
+		_, _ = fmt.Fprintf(r.stdout, "deleting clusterrole %s\n", role.Name)
+
+		err = k8sClients.K8sClient().RbacV1().ClusterRoles().Delete(ctx, role.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// deleteNamespace deletes the platform namespace when bootstrap created it.
+// Namespaces created by others, e.g. by a cluster admin for a namespaced
+// platform, are kept and only the objects bootstrap created in them are
+// deleted.
+func (r *runner) deleteNamespace(ctx context.Context, k8sClients k8sclient.Interface) error {
+	if !r.cluster.Namespaced {
+		namespace, err := k8sClients.K8sClient().CoreV1().Namespaces().Get(ctx, r.namespace(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if key.Selector(r.cluster.Instance).Matches(labels.Set(namespace.Labels)) {
+			_, _ = fmt.Fprintf(r.stdout, "deleting namespace %s\n", r.namespace())
+
+			err = k8sClients.K8sClient().CoreV1().Namespaces().Delete(ctx, r.namespace(), metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		}
+
+		_, _ = fmt.Fprintf(r.stdout, "keeping namespace %s, it was not created by bootstrap\n", r.namespace())
+	}
+
+	options := metav1.ListOptions{LabelSelector: key.Selector(r.cluster.Instance).String()}
+
+	err := k8sClients.K8sClient().CoreV1().ConfigMaps(r.namespace()).DeleteCollection(ctx, metav1.DeleteOptions{}, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	err = k8sClients.K8sClient().NetworkingV1().NetworkPolicies(r.namespace()).DeleteCollection(ctx, metav1.DeleteOptions{}, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// namespace is the namespace of the app platform instance selected with
+// --instance.
+func (r *runner) namespace() string {
+	return key.Namespace(r.cluster.Instance)
+}
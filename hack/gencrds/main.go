@@ -0,0 +1,147 @@
+// gencrds validates the CRD manifests synced into pkg/crds and writes them
+// as compressed JSON along with a generated index, so apptestctl embeds
+// compact, pre-validated CRDs instead of raw YAML. It runs in pkg/crds via
+// go generate and fails on any document which is not a valid CRD. Reading and
+// validating is done by pkg/crds/gen.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/apptestctl/pkg/crds/gen"
+)
+
+const (
+	dataDir    = "data"
+	outputFile = "zz_generated.crds.go"
+)
+
+var indexTemplate = template.Must(template.New("index").Parse(`// Code generated by hack/gencrds. DO NOT EDIT.
+
+package crds
+
+var all = []CRD{
+{{- range . }}
+	{
+		Name:     {{ printf "%q" .Name }},
+		Group:    {{ printf "%q" .Group }},
+		Plural:   {{ printf "%q" .Plural }},
+		Versions: []string{ {{- range $i, $v := .Versions }}{{ if $i }}, {{ end }}{{ printf "%q" $v }}{{ end -}} },
+		Digest:   {{ printf "%q" .Digest }},
+		file:     {{ printf "%q" .File }},
+	},
+{{- end }}
+}
+`))
+
+type crd struct {
+	Name     string
+	Group    string
+	Plural   string
+	Versions []string
+	Digest   string
+	File     string
+}
+
+func main() {
+	err := mainE()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", microerror.Pretty(err, true))
+		os.Exit(2)
+	}
+}
+
+func mainE() error {
+	crds, err := gen.Load(".")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.RemoveAll(dataDir)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = os.MkdirAll(dataDir, 0755)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var index []crd
+	manifests := map[string]bool{}
+	for _, c := range crds {
+		file := filepath.Join(dataDir, fmt.Sprintf("%s.json.gz", c.Name))
+
+		err = writeGzip(file, c.JSON)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		index = append(index, crd{
+			Name:     c.Name,
+			Group:    c.Group,
+			Plural:   c.Plural,
+			Versions: c.Versions,
+			Digest:   c.Digest,
+			File:     strings.ReplaceAll(file, string(filepath.Separator), "/"),
+		})
+		manifests[c.Manifest] = true
+	}
+
+	var buf bytes.Buffer
+	err = indexTemplate.Execute(&buf, index)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.WriteFile(outputFile, source, 0644) // #nosec G306
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// stdout is kept free for go generate callers, progress goes to stderr.
+	_, _ = fmt.Fprintf(os.Stderr, "generated %d CRDs from %d manifests\n", len(index), len(manifests))
+
+	return nil
+}
+
+// writeGzip compresses data into path. The gzip header carries no name or
+// modification time, so regenerating unchanged CRDs produces equal files.
+func writeGzip(path string, data []byte) error {
+	var buf bytes.Buffer
+
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.WriteFile(path, buf.Bytes(), 0644) // #nosec G306
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package appwait
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DeployedStatus     = "deployed"
+	DeletedStatus      = "deleted"
+	FailedStatus       = "failed"
+	NotInstalledStatus = "not-installed"
+
+	versionPrefix = "version="
+)
+
+// Condition is the state an App is waited for, e.g. deployed, deleted or
+// deployed in a specific version.
+type Condition struct {
+	Status  string
+	Version string
+}
+
+// ParseCondition parses deployed, deleted or version=X as accepted by
+// the --for flag.
+func ParseCondition(s string) (Condition, error) {
+	switch {
+	case s == DeployedStatus:
+		return Condition{Status: DeployedStatus}, nil
+	case s == DeletedStatus:
+		return Condition{Status: DeletedStatus}, nil
+	case strings.HasPrefix(s, versionPrefix) && len(s) > len(versionPrefix):
+		return Condition{Status: DeployedStatus, Version: strings.TrimPrefix(s, versionPrefix)}, nil
+	}
+
+	return Condition{}, microerror.Maskf(invalidConditionError, "%#q must be either %s, %s or %sX", s, DeployedStatus, DeletedStatus, versionPrefix)
+}
+
+func (c Condition) String() string {
+	if c.Version != "" {
+		return fmt.Sprintf("%s in version %s", c.Status, c.Version)
+	}
+
+	return c.Status
+}
+
+type Config struct {
+	Client client.WithWatch
+	Logger micrologger.Logger
+	// Stdout receives a line per observed status transition. Transitions
+	// are not printed when it is nil.
+	Stdout io.Writer
+
+	// ChartNamespace is the namespace app-operator creates the Chart CRs
+	// for in-cluster apps in, e.g. giantswarm.
+	ChartNamespace string
+}
+
+// Waiter watches an App CR and the Chart CR app-operator created for it
+// until a Condition is met.
+type Waiter struct {
+	client client.WithWatch
+	logger micrologger.Logger
+	stdout io.Writer
+
+	chartNamespace string
+}
+
+func New(config Config) (*Waiter, error) {
+	if config.Client == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Client must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.ChartNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartNamespace must not be empty", config)
+	}
+
+	w := &Waiter{
+		client: config.Client,
+		logger: config.Logger,
+		stdout: config.Stdout,
+
+		chartNamespace: config.ChartNamespace,
+	}
+
+	return w, nil
+}
+
+// state is the last observed status of the App and Chart CRs. Empty
+// statuses mean the CR does not exist.
+type state struct {
+	App          string
+	AppVersion   string
+	AppReason    string
+	Chart        string
+	ChartVersion string
+	ChartReason  string
+}
+
+// Wait blocks until the App CR meets the condition or ctx is done. It fails
+// right away when the release is failed or not installed, returning the
+// release reason.
+func (w *Waiter) Wait(ctx context.Context, namespace, name string, condition Condition) error {
+	w.logger.Debugf(ctx, "waiting for '%s/%s' app cr to be %s", namespace, name, condition)
+
+	var current state
+
+	// The watches are restarted whenever the API server closes them. Each
+	// round starts with a get so that deletions which happened in between
+	// are not missed.
+	for {
+		var err error
+
+		current, err = w.observe(ctx, namespace, name, current)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		done, err := evaluate(current, condition)
+		if err != nil {
+			return microerror.Mask(err)
+		} else if done {
+			break
+		}
+
+		current, done, err = w.watch(ctx, namespace, name, condition, current)
+		if err != nil {
+			return microerror.Mask(err)
+		} else if done {
+			break
+		}
+	}
+
+	w.logger.Debugf(ctx, "waited for '%s/%s' app cr to be %s", namespace, name, condition)
+
+	return nil
+}
+
+func (w *Waiter) observe(ctx context.Context, namespace, name string, current state) (state, error) {
+	next := current
+
+	var app v1alpha1.App
+	err := w.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &app)
+	if apierrors.IsNotFound(err) {
+		next.App, next.AppVersion, next.AppReason = "", "", ""
+	} else if err != nil {
+		return current, w.maskContextErr(ctx, err, current)
+	} else {
+		next.App, next.AppVersion, next.AppReason = appStatus(app)
+	}
+
+	var chart v1alpha1.Chart
+	err = w.client.Get(ctx, client.ObjectKey{Namespace: w.chartNamespace, Name: name}, &chart)
+	if apierrors.IsNotFound(err) {
+		next.Chart, next.ChartVersion, next.ChartReason = "", "", ""
+	} else if err != nil {
+		return current, w.maskContextErr(ctx, err, current)
+	} else {
+		next.Chart, next.ChartVersion, next.ChartReason = chartStatus(chart)
+	}
+
+	w.printTransitions(namespace, name, current, next)
+
+	return next, nil
+}
+
+func (w *Waiter) watch(ctx context.Context, namespace, name string, condition Condition, current state) (state, bool, error) {
+	appWatch, err := w.client.Watch(ctx, &v1alpha1.AppList{}, client.InNamespace(namespace), client.MatchingFields{"metadata.name": name})
+	if err != nil {
+		return current, false, w.maskContextErr(ctx, err, current)
+	}
+	defer appWatch.Stop()
+
+	chartWatch, err := w.client.Watch(ctx, &v1alpha1.ChartList{}, client.InNamespace(w.chartNamespace), client.MatchingFields{"metadata.name": name})
+	if err != nil {
+		return current, false, w.maskContextErr(ctx, err, current)
+	}
+	defer chartWatch.Stop()
+
+	for {
+		next := current
+
+		select {
+		case <-ctx.Done():
+			return current, false, w.maskContextErr(ctx, ctx.Err(), current)
+
+		case event, ok := <-appWatch.ResultChan():
+			if !ok {
+				w.logger.Debugf(ctx, "app cr watch closed, restarting")
+				return current, false, nil
+			}
+
+			switch event.Type {
+			case watch.Deleted:
+				next.App, next.AppVersion, next.AppReason = "", "", ""
+			case watch.Added, watch.Modified:
+				app, ok := event.Object.(*v1alpha1.App)
+				if !ok {
+					continue
+				}
+				next.App, next.AppVersion, next.AppReason = appStatus(*app)
+			case watch.Error:
+				w.logger.Debugf(ctx, "app cr watch failed with %s, restarting", apierrors.FromObject(event.Object))
+				return current, false, nil
+			}
+
+		case event, ok := <-chartWatch.ResultChan():
+			if !ok {
+				w.logger.Debugf(ctx, "chart cr watch closed, restarting")
+				return current, false, nil
+			}
+
+			switch event.Type {
+			case watch.Deleted:
+				next.Chart, next.ChartVersion, next.ChartReason = "", "", ""
+			case watch.Added, watch.Modified:
+				chart, ok := event.Object.(*v1alpha1.Chart)
+				if !ok {
+					continue
+				}
+				next.Chart, next.ChartVersion, next.ChartReason = chartStatus(*chart)
+			case watch.Error:
+				w.logger.Debugf(ctx, "chart cr watch failed with %s, restarting", apierrors.FromObject(event.Object))
+				return current, false, nil
+			}
+		}
+
+		w.printTransitions(namespace, name, current, next)
+		current = next
+
+		done, err := evaluate(current, condition)
+		if err != nil {
+			return current, false, microerror.Mask(err)
+		} else if done {
+			return current, true, nil
+		}
+	}
+}
+
+func (w *Waiter) printTransitions(namespace, name string, current, next state) {
+	if w.stdout == nil {
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+
+	if current.App != next.App || current.AppVersion != next.AppVersion {
+		_, _ = fmt.Fprintf(w.stdout, "%s app %s/%s: %s\n", now, namespace, name, formatStatus(next.App, next.AppVersion, next.AppReason))
+	}
+	if current.Chart != next.Chart || current.ChartVersion != next.ChartVersion {
+		_, _ = fmt.Fprintf(w.stdout, "%s chart %s/%s: %s\n", now, w.chartNamespace, name, formatStatus(next.Chart, next.ChartVersion, next.ChartReason))
+	}
+}
+
+// maskContextErr turns errors caused by an expired ctx into a timeout error
+// carrying the last observed status.
+func (w *Waiter) maskContextErr(ctx context.Context, err error, current state) error {
+	if ctx.Err() == nil {
+		return microerror.Mask(err)
+	}
+
+	return microerror.Maskf(timeoutError, "app status %s, chart status %s", formatStatus(current.App, current.AppVersion, current.AppReason), formatStatus(current.Chart, current.ChartVersion, current.ChartReason))
+}
+
+func evaluate(current state, condition Condition) (bool, error) {
+	switch condition.Status {
+	case DeletedStatus:
+		return current.App == "" && current.Chart == "", nil
+	case DeployedStatus:
+		switch current.App {
+		case FailedStatus, NotInstalledStatus:
+			reason := current.AppReason
+			if reason == "" {
+				reason = current.ChartReason
+			}
+
+			return false, microerror.Maskf(releaseFailedError, "release is %#q: %s", current.App, reason)
+		case DeployedStatus:
+			return condition.Version == "" || condition.Version == current.AppVersion, nil
+		}
+	}
+
+	return false, nil
+}
+
+func appStatus(app v1alpha1.App) (string, string, string) {
+	status := app.Status.Release.Status
+	if status == "" {
+		// The App CR exists but app-operator did not report a status yet.
+		status = "pending"
+	}
+
+	return status, app.Status.Version, app.Status.Release.Reason
+}
+
+func chartStatus(chart v1alpha1.Chart) (string, string, string) {
+	status := chart.Status.Release.Status
+	if status == "" {
+		status = "pending"
+	}
+
+	return status, chart.Status.Version, chart.Status.Reason
+}
+
+func formatStatus(status, version, reason string) string {
+	if status == "" {
+		return "not found"
+	}
+
+	s := status
+	if version != "" {
+		s = fmt.Sprintf("%s (version %s)", s, version)
+	}
+	if reason != "" {
+		s = fmt.Sprintf("%s: %s", s, reason)
+	}
+
+	return s
+}
@@ -0,0 +1,39 @@
+package appwait
+
+import "github.com/giantswarm/microerror"
+
+var invalidConditionError = &microerror.Error{
+	Kind: "invalidConditionError",
+}
+
+// IsInvalidCondition asserts invalidConditionError.
+func IsInvalidCondition(err error) bool {
+	return microerror.Cause(err) == invalidConditionError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var releaseFailedError = &microerror.Error{
+	Kind: "releaseFailedError",
+}
+
+// IsReleaseFailed asserts releaseFailedError.
+func IsReleaseFailed(err error) bool {
+	return microerror.Cause(err) == releaseFailedError
+}
+
+var timeoutError = &microerror.Error{
+	Kind: "timeoutError",
+}
+
+// IsTimeout asserts timeoutError.
+func IsTimeout(err error) bool {
+	return microerror.Cause(err) == timeoutError
+}
@@ -0,0 +1,12 @@
+package await
+
+import "github.com/giantswarm/microerror"
+
+var timeoutExceededError = &microerror.Error{
+	Kind: "timeoutExceededError",
+}
+
+// IsTimeoutExceeded asserts timeoutExceededError.
+func IsTimeoutExceeded(err error) bool {
+	return microerror.Cause(err) == timeoutExceededError
+}
@@ -0,0 +1,113 @@
+// Package await waits for cluster state, reacting to watch events and polling
+// as a fallback.
+package await
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// DiscoveryInterval is how often API discovery is checked. Discovery
+	// cannot be watched, but it is refreshed shortly after a CRD is
+	// established, so a short interval keeps the wait close to the refresh.
+	DiscoveryInterval = 250 * time.Millisecond
+	// FallbackInterval is how often conditions are checked when the watch
+	// reports nothing, e.g. because it could not be opened or events were
+	// missed.
+	FallbackInterval = 5 * time.Second
+)
+
+// Condition reports whether the awaited state is reached. The returned
+// error is not fatal, it is kept as the reason in case the wait times out.
+type Condition func(ctx context.Context) (bool, error)
+
+// WatchFunc opens a watch whose events indicate the condition may have
+// changed.
+type WatchFunc func(ctx context.Context) (watch.Interface, error)
+
+// For checks the condition every time the watch reports an event and every
+// interval, until the condition is met or timeout passes. The watch is
+// reopened when the API server closes it. Without a watch the condition is
+// only polled.
+func For(ctx context.Context, logger micrologger.Logger, description string, timeout, interval time.Duration, done Condition, newWatch WatchFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var w watch.Interface
+	defer func() {
+		if w != nil {
+			w.Stop()
+		}
+	}()
+
+	var reason error
+	for {
+		// The watch is opened before the condition is checked, so changes
+		// happening in between are not missed.
+		if w == nil && newWatch != nil {
+			var err error
+			w, err = newWatch(ctx)
+			if ctx.Err() != nil {
+				return timeoutError(ctx, description, timeout, reason)
+			} else if err != nil {
+				logger.Debugf(ctx, "failed to watch %s, polling instead: %s", description, err)
+				w = nil
+			}
+		}
+
+		ok, err := done(ctx)
+		if ctx.Err() != nil {
+			return timeoutError(ctx, description, timeout, reason)
+		} else if err != nil {
+			reason = err
+			logger.Debugf(ctx, "waiting for %s: %s", description, err)
+		} else if ok {
+			return nil
+		}
+
+		var events <-chan watch.Event
+		if w != nil {
+			events = w.ResultChan()
+		}
+
+		select {
+		case <-ctx.Done():
+			return timeoutError(ctx, description, timeout, reason)
+		case _, open := <-events:
+			if !open {
+				logger.Debugf(ctx, "watch for %s closed, reopening", description)
+				w.Stop()
+				w = nil
+			}
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NameSelector selects a single object by name in list and watch calls.
+func NameSelector(name string) metav1.ListOptions {
+	return metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+}
+
+// timeoutError masks the last reason the condition was not met, so users
+// see why the wait did not finish instead of a bare context error.
+func timeoutError(ctx context.Context, description string, timeout time.Duration, reason error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return microerror.Mask(ctx.Err())
+	}
+	if reason == nil {
+		return microerror.Maskf(timeoutExceededError, "timed out after %s waiting for %s: %s", timeout, description, ctx.Err())
+	}
+
+	return microerror.Maskf(timeoutExceededError, "timed out after %s waiting for %s: %s", timeout, description, reason)
+}
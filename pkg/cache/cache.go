@@ -0,0 +1,542 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	KindChart = "chart"
+	KindIndex = "index"
+
+	blobsDir = "blobs"
+	refsDir  = "refs"
+
+	digestAlgorithm = "sha256"
+
+	defaultHTTPTimeout = 30 * time.Second
+)
+
+// PullFunc downloads the chart tarball at the given URL to a temporary file
+// and returns its path, e.g. helmclient.Interface.PullChartTarball.
+type PullFunc func(ctx context.Context, url string) (string, error)
+
+type Config struct {
+	HTTPClient *http.Client
+	Logger     micrologger.Logger
+
+	// Dir is the cache directory. See DefaultDir.
+	Dir string
+}
+
+// Cache is a content-addressed on-disk cache for catalog indexes and chart
+// tarballs. Content is stored once per digest in the blobs directory, while
+// refs map the source URL to the blob along with the metadata needed to
+// revalidate it.
+type Cache struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	dir string
+}
+
+// Entry is the metadata of a cached index or chart tarball.
+type Entry struct {
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+	// Digest is the digest of the cached content, e.g. "sha256:abc...".
+	Digest       string    `json:"digest"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Size         int64     `json:"size"`
+	Created      time.Time `json:"created"`
+	LastUsed     time.Time `json:"lastUsed"`
+
+	ref string
+}
+
+// DefaultDir returns the apptestctl directory in the user cache dir, falling
+// back to the temp dir when the user cache dir cannot be determined.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "apptestctl")
+}
+
+func New(config Config) (*Cache, error) {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Dir == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Dir must not be empty", config)
+	}
+
+	c := &Cache{
+		httpClient: config.HTTPClient,
+		logger:     config.Logger,
+
+		dir: config.Dir,
+	}
+
+	return c, nil
+}
+
+// Index returns the index.yaml of the Helm catalog at catalogURL. A cached
+// index is revalidated using its ETag and Last-Modified headers and is also
+// used when the catalog cannot be reached.
+func (c *Cache) Index(ctx context.Context, catalogURL string) ([]byte, error) {
+	indexURL := fmt.Sprintf("%s/index.yaml", strings.TrimSuffix(catalogURL, "/"))
+	ref := refName(KindIndex, indexURL, "")
+
+	cached, err := c.readRef(ref)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if cached != nil {
+		// The blob may have been pruned by another run.
+		_, err = os.Stat(c.blobPath(cached.Digest))
+		if os.IsNotExist(err) {
+			cached = nil
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil && cached != nil {
+		c.logger.Errorf(ctx, err, "failed to fetch %#q, using cached index", indexURL)
+		return c.useBlob(cached)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		c.logger.Debugf(ctx, "cached index for %#q is up to date", indexURL)
+		return c.useBlob(cached)
+	case resp.StatusCode != http.StatusOK && cached != nil:
+		c.logger.Debugf(ctx, "fetching %#q returned status %d, using cached index", indexURL, resp.StatusCode)
+		return c.useBlob(cached)
+	case resp.StatusCode != http.StatusOK:
+		return nil, microerror.Maskf(executionFailedError, "fetching %#q returned status %d", indexURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	digest, err := c.writeBlob(body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now().UTC()
+	e := Entry{
+		Kind:         KindIndex,
+		URL:          indexURL,
+		Digest:       digest,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Size:         int64(len(body)),
+		Created:      now,
+		LastUsed:     now,
+
+		ref: ref,
+	}
+	err = c.writeRef(e)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c.logger.Debugf(ctx, "cached index for %#q", indexURL)
+
+	return body, nil
+}
+
+// Chart returns the path of a copy of the cached chart tarball for
+// tarballURL, pulling it with pull when it is not cached yet. The tarball is
+// keyed by URL and digest. When digest is set the pulled tarball is verified
+// against it. The copy is owned by the caller, who must remove it, so
+// concurrent prunes cannot remove it while it is in use.
+func (c *Cache) Chart(ctx context.Context, tarballURL, digest string, pull PullFunc) (string, error) {
+	digest = normalizeDigest(digest)
+	ref := refName(KindChart, tarballURL, digest)
+
+	cached, err := c.readRef(ref)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if cached != nil {
+		body, err := os.ReadFile(c.blobPath(cached.Digest))
+		if err == nil {
+			c.logger.Debugf(ctx, "using cached tarball for %#q", tarballURL)
+
+			cached.LastUsed = time.Now().UTC()
+			err = c.writeRef(*cached)
+			if err != nil {
+				return "", microerror.Mask(err)
+			}
+
+			path, err := writeTempFile(body)
+			if err != nil {
+				return "", microerror.Mask(err)
+			}
+
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", microerror.Mask(err)
+		}
+	}
+
+	c.logger.Debugf(ctx, "pulling tarball %#q", tarballURL)
+
+	tmpPath, err := pull(ctx, tarballURL)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	body, err := os.ReadFile(tmpPath) // #nosec G304
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	blobDigest := digestOf(body)
+	if digest != "" && digest != blobDigest {
+		return "", microerror.Maskf(digestMismatchError, "tarball %#q has digest %#q but %#q was expected", tarballURL, blobDigest, digest)
+	}
+
+	_, err = c.writeBlob(body)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	now := time.Now().UTC()
+	e := Entry{
+		Kind:     KindChart,
+		URL:      tarballURL,
+		Digest:   blobDigest,
+		Size:     int64(len(body)),
+		Created:  now,
+		LastUsed: now,
+
+		ref: ref,
+	}
+	err = c.writeRef(e)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	c.logger.Debugf(ctx, "cached tarball for %#q", tarballURL)
+
+	path, err := writeTempFile(body)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return path, nil
+}
+
+// List returns all cached entries sorted by kind and URL. Listing a cache
+// dir which does not exist yet returns no entries.
+func (c *Cache) List() ([]Entry, error) {
+	files, err := os.ReadDir(filepath.Join(c.dir, refsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+
+		e, err := c.readRef(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if e != nil {
+			entries = append(entries, *e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].URL < entries[j].URL
+	})
+
+	return entries, nil
+}
+
+// Prune removes all entries which were not used within olderThan and then
+// removes blobs no longer referenced by any entry. A zero olderThan removes
+// all entries. The removed entries are returned.
+//
+// Runs sharing the cache dir may add entries while pruning. Blobs are only
+// removed when no entry references them after the entries were removed and
+// they were not written or reused since the prune started.
+func (c *Cache) Prune(olderThan time.Duration) ([]Entry, error) {
+	start := time.Now()
+
+	entries, err := c.List()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	threshold := start.UTC().Add(-olderThan)
+
+	var pruned []Entry
+	for _, e := range entries {
+		if olderThan > 0 && e.LastUsed.After(threshold) {
+			continue
+		}
+
+		err = os.Remove(c.refPath(e.ref))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, microerror.Mask(err)
+		}
+
+		pruned = append(pruned, e)
+	}
+
+	remaining, err := c.List()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	referenced := map[string]bool{}
+	for _, e := range remaining {
+		referenced[e.Digest] = true
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(c.dir, blobsDir, digestAlgorithm))
+	if os.IsNotExist(err) {
+		return pruned, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, b := range blobs {
+		// Temporary files are blobs other runs are writing.
+		if b.IsDir() || strings.HasPrefix(b.Name(), ".") {
+			continue
+		}
+
+		digest := fmt.Sprintf("%s:%s", digestAlgorithm, b.Name())
+		if referenced[digest] {
+			continue
+		}
+
+		info, err := b.Info()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !info.ModTime().Before(start) {
+			continue
+		}
+
+		err = os.Remove(c.blobPath(digest))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return pruned, nil
+}
+
+func (c *Cache) useBlob(e *Entry) ([]byte, error) {
+	body, err := os.ReadFile(c.blobPath(e.Digest))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	e.LastUsed = time.Now().UTC()
+	err = c.writeRef(*e)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return body, nil
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.dir, blobsDir, digestAlgorithm, strings.TrimPrefix(digest, digestAlgorithm+":"))
+}
+
+func (c *Cache) refPath(ref string) string {
+	return filepath.Join(c.dir, refsDir, ref+".json")
+}
+
+// readRef returns nil without error when the ref does not exist.
+func (c *Cache) readRef(ref string) (*Entry, error) {
+	bytes, err := os.ReadFile(c.refPath(ref))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var e Entry
+	err = json.Unmarshal(bytes, &e)
+	if err != nil {
+		// A corrupt ref is treated like a cache miss and gets overwritten.
+		return nil, nil
+	}
+	e.ref = ref
+
+	return &e, nil
+}
+
+func (c *Cache) writeRef(e Entry) error {
+	bytes, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = writeFileAtomic(c.refPath(e.ref), bytes)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Cache) writeBlob(body []byte) (string, error) {
+	digest := digestOf(body)
+
+	// Existing blobs are touched, so a concurrent prune does not remove them
+	// before the ref pointing to them is written.
+	path := c.blobPath(digest)
+	now := time.Now()
+	err := os.Chtimes(path, now, now)
+	if err == nil {
+		return digest, nil
+	} else if !os.IsNotExist(err) {
+		return "", microerror.Mask(err)
+	}
+
+	err = writeFileAtomic(path, body)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return digest, nil
+}
+
+// writeFileAtomic writes to a temporary file and renames it, so concurrent
+// runs sharing a cache dir never observe partially written files. Missing
+// cache dirs are created.
+func writeFileAtomic(path string, body []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.Write(body)
+	if err != nil {
+		_ = f.Close()
+		return microerror.Mask(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// writeTempFile writes body to a new file in the temp dir and returns its
+// path.
+func writeTempFile(body []byte) (string, error) {
+	f, err := os.CreateTemp("", "chart-tarball")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	_, err = f.Write(body)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", microerror.Mask(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", microerror.Mask(err)
+	}
+
+	return f.Name(), nil
+}
+
+func refName(kind, url, digest string) string {
+	sum := sha256.Sum256([]byte(kind + "\n" + url + "\n" + digest))
+	return hex.EncodeToString(sum[:])
+}
+
+func digestOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf("%s:%s", digestAlgorithm, hex.EncodeToString(sum[:]))
+}
+
+// normalizeDigest prefixes bare hex digests as found in Helm index files with
+// the digest algorithm.
+func normalizeDigest(digest string) string {
+	if digest == "" || strings.Contains(digest, ":") {
+		return digest
+	}
+
+	return fmt.Sprintf("%s:%s", digestAlgorithm, digest)
+}
@@ -0,0 +1,404 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+func Test_New_SideEffectFree(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "apptestctl")
+
+	c := newTestCache(t, dir)
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected %#v got %#v", 0, len(entries))
+	}
+
+	pruned, err := c.Prune(0)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	if len(pruned) != 0 {
+		t.Fatalf("expected %#v got %#v", 0, len(pruned))
+	}
+
+	_, err = os.Stat(dir)
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected cache dir %#q not to exist got %#v", dir, err)
+	}
+}
+
+func Test_Cache_Index(t *testing.T) {
+	lastModified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat)
+
+	testCases := []struct {
+		name         string
+		etag         string
+		lastModified string
+		// status and body are the response to the revalidation request.
+		status                  int
+		body                    string
+		expectedIfNoneMatch     string
+		expectedIfModifiedSince string
+		expectedBody            string
+	}{
+		{
+			name:                "case 0: revalidated with ETag",
+			etag:                `"v1"`,
+			status:              http.StatusNotModified,
+			expectedIfNoneMatch: `"v1"`,
+			expectedBody:        "v1",
+		},
+		{
+			name:                    "case 1: revalidated with Last-Modified",
+			lastModified:            lastModified,
+			status:                  http.StatusNotModified,
+			expectedIfModifiedSince: lastModified,
+			expectedBody:            "v1",
+		},
+		{
+			name:                "case 2: changed index",
+			etag:                `"v1"`,
+			status:              http.StatusOK,
+			body:                "v2",
+			expectedIfNoneMatch: `"v1"`,
+			expectedBody:        "v2",
+		},
+		{
+			name:                "case 3: cached index used when the catalog fails",
+			etag:                `"v1"`,
+			status:              http.StatusInternalServerError,
+			expectedIfNoneMatch: `"v1"`,
+			expectedBody:        "v1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []*http.Request
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+
+				if len(requests) == 1 {
+					if tc.etag != "" {
+						w.Header().Set("ETag", tc.etag)
+					}
+					if tc.lastModified != "" {
+						w.Header().Set("Last-Modified", tc.lastModified)
+					}
+					_, _ = w.Write([]byte("v1"))
+					return
+				}
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			c := newTestCache(t, t.TempDir())
+
+			body, err := c.Index(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			if string(body) != "v1" {
+				t.Fatalf("expected %#v got %#v", "v1", string(body))
+			}
+
+			body, err = c.Index(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			if string(body) != tc.expectedBody {
+				t.Fatalf("expected %#v got %#v", tc.expectedBody, string(body))
+			}
+
+			if len(requests) != 2 {
+				t.Fatalf("expected %#v got %#v", 2, len(requests))
+			}
+			if requests[1].URL.Path != "/index.yaml" {
+				t.Fatalf("expected %#v got %#v", "/index.yaml", requests[1].URL.Path)
+			}
+			ifNoneMatch := requests[1].Header.Get("If-None-Match")
+			if ifNoneMatch != tc.expectedIfNoneMatch {
+				t.Fatalf("expected %#v got %#v", tc.expectedIfNoneMatch, ifNoneMatch)
+			}
+			ifModifiedSince := requests[1].Header.Get("If-Modified-Since")
+			if ifModifiedSince != tc.expectedIfModifiedSince {
+				t.Fatalf("expected %#v got %#v", tc.expectedIfModifiedSince, ifModifiedSince)
+			}
+		})
+	}
+}
+
+func Test_Cache_LatestChart(t *testing.T) {
+	index := `entries:
+  app-operator:
+  - name: app-operator
+    version: 6.7.0
+    created: 2026-01-01T00:00:00Z
+    digest: abc
+    urls:
+    - app-operator-6.7.0.tgz
+  - name: app-operator
+    version: 6.8.0
+    created: 2026-02-01T00:00:00Z
+    digest: def
+    urls:
+    - https://mirror.example.com/app-operator-6.8.0.tgz
+  - name: app-operator
+    version: 6.7.0-abc123
+    created: 2026-03-01T00:00:00Z
+    urls: []
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(index))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name            string
+		chart           string
+		version         string
+		expectedVersion string
+		expectedURL     string
+		errorMatcher    func(error) bool
+	}{
+		{
+			name:            "case 0: latest version",
+			chart:           "app-operator",
+			expectedVersion: "6.8.0",
+			expectedURL:     "https://mirror.example.com/app-operator-6.8.0.tgz",
+		},
+		{
+			name:            "case 1: version with relative URL, skipping versions without URLs",
+			chart:           "app-operator",
+			version:         "6.7.0",
+			expectedVersion: "6.7.0",
+			expectedURL:     server.URL + "/catalog/app-operator-6.7.0.tgz",
+		},
+		{
+			name:         "case 2: missing version",
+			chart:        "app-operator",
+			version:      "7.0.0",
+			errorMatcher: IsNotFound,
+		},
+		{
+			name:         "case 3: missing chart",
+			chart:        "chart-operator",
+			errorMatcher: IsNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestCache(t, t.TempDir())
+
+			chart, err := c.LatestChart(context.Background(), server.URL+"/catalog/", tc.chart, tc.version)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if chart.Version != tc.expectedVersion {
+				t.Fatalf("expected %#v got %#v", tc.expectedVersion, chart.Version)
+			}
+			if chart.URLs[0] != tc.expectedURL {
+				t.Fatalf("expected %#v got %#v", tc.expectedURL, chart.URLs[0])
+			}
+		})
+	}
+}
+
+func Test_Cache_Chart(t *testing.T) {
+	tarball := []byte("chart tarball")
+
+	var pulls int
+	pull := func(ctx context.Context, url string) (string, error) {
+		pulls++
+		return writeTempFile(tarball)
+	}
+
+	c := newTestCache(t, t.TempDir())
+
+	var paths []string
+	for i := 0; i < 2; i++ {
+		path, err := c.Chart(context.Background(), "https://example.com/app-operator-6.7.0.tgz", digestOf(tarball), pull)
+		if err != nil {
+			t.Fatalf("expected %#v got %#v", nil, err)
+		}
+		defer func() { _ = os.Remove(path) }()
+
+		paths = append(paths, path)
+	}
+
+	if pulls != 1 {
+		t.Fatalf("expected %#v got %#v", 1, pulls)
+	}
+	if paths[0] == paths[1] {
+		t.Fatalf("expected distinct copies got %#v", paths)
+	}
+
+	// Pruning everything must not remove the copies handed out.
+	_, err := c.Prune(0)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected %#v got %#v", nil, err)
+		}
+		if string(body) != string(tarball) {
+			t.Fatalf("expected %q got %q", tarball, body)
+		}
+	}
+
+	_, err = c.Chart(context.Background(), "https://example.com/app-operator-6.8.0.tgz", digestOf([]byte("other")), pull)
+	if !IsDigestMismatch(err) {
+		t.Fatalf("expected %#v got %#v", digestMismatchError, err)
+	}
+}
+
+func Test_Cache_Prune(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCache(t, dir)
+
+	start := time.Now()
+	old := start.Add(-48 * time.Hour)
+
+	recentDigest := writeTestEntry(t, c, "https://example.com/recent.tgz", "recent", start.UTC())
+	oldDigest := writeTestEntry(t, c, "https://example.com/old.tgz", "old", old.UTC())
+	// A blob shared by an old and a recent entry is still referenced.
+	sharedDigest := writeTestEntry(t, c, "https://example.com/shared-old.tgz", "shared", old.UTC())
+	writeTestEntry(t, c, "https://example.com/shared-recent.tgz", "shared", start.UTC())
+
+	// Blobs written before the prune started and not referenced are removed,
+	// while blobs written concurrently and temporary files are kept.
+	orphanDigest, err := c.writeBlob([]byte("orphan"))
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	for _, d := range []string{recentDigest, oldDigest, sharedDigest, orphanDigest} {
+		err = os.Chtimes(c.blobPath(d), old, old)
+		if err != nil {
+			t.Fatalf("expected %#v got %#v", nil, err)
+		}
+	}
+	concurrentDigest, err := c.writeBlob([]byte("concurrent"))
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	future := start.Add(time.Hour)
+	err = os.Chtimes(c.blobPath(concurrentDigest), future, future)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	tmpPath := filepath.Join(dir, blobsDir, digestAlgorithm, ".tmp-123")
+	err = os.WriteFile(tmpPath, []byte("partial"), 0o600)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	pruned, err := c.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	var prunedURLs []string
+	for _, e := range pruned {
+		prunedURLs = append(prunedURLs, e.URL)
+	}
+	expectedURLs := []string{"https://example.com/old.tgz", "https://example.com/shared-old.tgz"}
+	if fmt.Sprint(prunedURLs) != fmt.Sprint(expectedURLs) {
+		t.Fatalf("expected %#v got %#v", expectedURLs, prunedURLs)
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected %#v got %#v", 2, len(entries))
+	}
+
+	expectedBlobs := map[string]bool{
+		recentDigest:     true,
+		oldDigest:        false,
+		sharedDigest:     true,
+		orphanDigest:     false,
+		concurrentDigest: true,
+	}
+	for d, expected := range expectedBlobs {
+		_, err := os.Stat(c.blobPath(d))
+		if exists := err == nil; exists != expected {
+			t.Fatalf("expected blob %#q to exist %#v got %#v", d, expected, exists)
+		}
+	}
+
+	_, err = os.Stat(tmpPath)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+}
+
+func newTestCache(t *testing.T, dir string) *Cache {
+	c, err := New(Config{
+		Logger: microloggertest.New(),
+		Dir:    dir,
+	})
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	return c
+}
+
+func writeTestEntry(t *testing.T, c *Cache, url, body string, lastUsed time.Time) string {
+	digest, err := c.writeBlob([]byte(body))
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	e := Entry{
+		Kind:     KindChart,
+		URL:      url,
+		Digest:   digest,
+		Size:     int64(len(body)),
+		Created:  lastUsed,
+		LastUsed: lastUsed,
+
+		ref: refName(KindChart, url, ""),
+	}
+	err = c.writeRef(e)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	return digest
+}
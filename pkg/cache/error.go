@@ -0,0 +1,39 @@
+package cache
+
+import "github.com/giantswarm/microerror"
+
+var digestMismatchError = &microerror.Error{
+	Kind: "digestMismatchError",
+}
+
+// IsDigestMismatch asserts digestMismatchError.
+func IsDigestMismatch(err error) bool {
+	return microerror.Cause(err) == digestMismatchError
+}
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
+}
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/yaml"
+)
+
+type index struct {
+	Entries map[string][]ChartVersion `json:"entries"`
+}
+
+// ChartVersion is a single chart version in a Helm catalog index.
+type ChartVersion struct {
+	Created time.Time `json:"created"`
+	Digest  string    `json:"digest"`
+	Name    string    `json:"name"`
+	URLs    []string  `json:"urls"`
+	Version string    `json:"version"`
+}
+
+// LatestChart returns the most recently created version of the chart in the
+// catalog whose version ends with version, mirroring
+// appcatalog.GetLatestEntry. The returned URLs are absolute.
+func (c *Cache) LatestChart(ctx context.Context, catalogURL, name, version string) (ChartVersion, error) {
+	body, err := c.Index(ctx, catalogURL)
+	if err != nil {
+		return ChartVersion{}, microerror.Mask(err)
+	}
+
+	var i index
+	err = yaml.Unmarshal(body, &i)
+	if err != nil {
+		return ChartVersion{}, microerror.Mask(err)
+	}
+
+	var latest *ChartVersion
+	for n, cv := range i.Entries[name] {
+		if version != "" && !strings.HasSuffix(cv.Version, version) {
+			continue
+		}
+		if len(cv.URLs) == 0 {
+			continue
+		}
+		if latest == nil || cv.Created.After(latest.Created) {
+			latest = &i.Entries[name][n]
+		}
+	}
+	if latest == nil {
+		return ChartVersion{}, microerror.Maskf(notFoundError, "no chart %#q with version %#q in index of %#q", name, version, catalogURL)
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(catalogURL, "/") + "/")
+	if err != nil {
+		return ChartVersion{}, microerror.Mask(err)
+	}
+	for n, u := range latest.URLs {
+		ref, err := url.Parse(u)
+		if err != nil {
+			return ChartVersion{}, microerror.Mask(err)
+		}
+		latest.URLs[n] = base.ResolveReference(ref).String()
+	}
+
+	return *latest, nil
+}
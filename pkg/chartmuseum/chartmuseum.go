@@ -0,0 +1,229 @@
+package chartmuseum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	defaultHTTPTimeout = 60 * time.Second
+)
+
+type Config struct {
+	K8sClient  kubernetes.Interface
+	Logger     micrologger.Logger
+	RestConfig *rest.Config
+
+	// Namespace and Service identify the chartmuseum service, e.g.
+	// giantswarm and chartmuseum.
+	Namespace string
+	Service   string
+}
+
+// Client talks to the chartmuseum API of the in-cluster chartmuseum. Every
+// call opens its own port-forward through the API server, so no ingress or
+// NodePort is needed.
+type Client struct {
+	httpClient *http.Client
+	k8sClient  kubernetes.Interface
+	logger     micrologger.Logger
+	restConfig *rest.Config
+
+	namespace string
+	service   string
+}
+
+func New(config Config) (*Client, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.RestConfig == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RestConfig must not be empty", config)
+	}
+
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Namespace must not be empty", config)
+	}
+	if config.Service == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+	}
+
+	c := &Client{
+		httpClient: &http.Client{Timeout: defaultHTTPTimeout},
+		k8sClient:  config.K8sClient,
+		logger:     config.Logger,
+		restConfig: config.RestConfig,
+
+		namespace: config.Namespace,
+		service:   config.Service,
+	}
+
+	return c, nil
+}
+
+// ChartVersion is a chart version as returned by the chartmuseum API.
+type ChartVersion struct {
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+	Digest  string    `json:"digest"`
+	Created time.Time `json:"created"`
+}
+
+// Push uploads a packaged chart tarball.
+func (c *Client) Push(ctx context.Context, tarball []byte) error {
+	baseURL, stop, err := c.forward(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer stop()
+
+	resp, err := c.do(ctx, baseURL, http.MethodPost, "/api/charts", bytes.NewReader(tarball))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return nil
+	case http.StatusConflict:
+		return microerror.Maskf(alreadyExistsError, "%s", resp.Body)
+	default:
+		return microerror.Maskf(executionFailedError, "pushing chart returned status %d: %s", resp.StatusCode, resp.Body)
+	}
+}
+
+// List returns all chart versions sorted by name and creation time, newest
+// first.
+func (c *Client) List(ctx context.Context) ([]ChartVersion, error) {
+	baseURL, stop, err := c.forward(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer stop()
+
+	resp, err := c.do(ctx, baseURL, http.MethodGet, "/api/charts", nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(executionFailedError, "listing charts returned status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	var charts map[string][]ChartVersion
+	err = json.Unmarshal(resp.Body, &charts)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var versions []ChartVersion
+	for _, cvs := range charts {
+		versions = append(versions, cvs...)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Name != versions[j].Name {
+			return versions[i].Name < versions[j].Name
+		}
+		return versions[i].Created.After(versions[j].Created)
+	})
+
+	return versions, nil
+}
+
+// Delete removes the given version of the chart or all its versions when
+// version is empty. The deleted versions are returned.
+func (c *Client) Delete(ctx context.Context, name, version string) ([]string, error) {
+	baseURL, stop, err := c.forward(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer stop()
+
+	versions := []string{version}
+	if version == "" {
+		resp, err := c.do(ctx, baseURL, http.MethodGet, fmt.Sprintf("/api/charts/%s", url.PathEscape(name)), nil)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, microerror.Maskf(notFoundError, "chart %#q not found", name)
+		} else if resp.StatusCode != http.StatusOK {
+			return nil, microerror.Maskf(executionFailedError, "getting chart %#q returned status %d: %s", name, resp.StatusCode, resp.Body)
+		}
+
+		var cvs []ChartVersion
+		err = json.Unmarshal(resp.Body, &cvs)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		versions = nil
+		for _, cv := range cvs {
+			versions = append(versions, cv.Version)
+		}
+	}
+
+	var deleted []string
+	for _, v := range versions {
+		resp, err := c.do(ctx, baseURL, http.MethodDelete, fmt.Sprintf("/api/charts/%s/%s", url.PathEscape(name), url.PathEscape(v)), nil)
+		if err != nil {
+			return deleted, microerror.Mask(err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return deleted, microerror.Maskf(notFoundError, "chart %#q version %#q not found", name, v)
+		} else if resp.StatusCode != http.StatusOK {
+			return deleted, microerror.Maskf(executionFailedError, "deleting chart %#q version %#q returned status %d: %s", name, v, resp.StatusCode, resp.Body)
+		}
+
+		deleted = append(deleted, v)
+	}
+
+	return deleted, nil
+}
+
+type response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// do sends a request to the chartmuseum API at baseURL, which is the local
+// end of a port-forward opened via forward.
+func (c *Client) do(ctx context.Context, baseURL, method, path string, body io.Reader) (response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", baseURL, path), body)
+	if err != nil {
+		return response{}, microerror.Mask(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	c.logger.Debugf(ctx, "sending %s %s to chartmuseum", method, path)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return response{}, microerror.Mask(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response{}, microerror.Mask(err)
+	}
+
+	return response{StatusCode: resp.StatusCode, Body: b}, nil
+}
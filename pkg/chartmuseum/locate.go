@@ -0,0 +1,47 @@
+package chartmuseum
+
+import (
+	"context"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+// Locate returns the namespace and name of the chartmuseum service of the
+// app platform instance, to be used as Config.Namespace and Config.Service.
+// The chartmuseum App CR is selected by the labels bootstrap sets in the App
+// CR namespace of the platform, so chartmuseum apps not installed by
+// apptestctl are never used.
+func Locate(ctx context.Context, ctrlClient client.Client, instance string, namespaced bool) (string, string, error) {
+	namespace := key.AppCRNamespace(instance, namespaced)
+
+	requirement, err := labels.NewRequirement(label.AppKubernetesName, selection.Equals, []string{key.ChartMuseumName()})
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	var apps v1alpha1.AppList
+	err = ctrlClient.List(ctx, &apps,
+		client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: key.PlatformSelector(instance).Add(*requirement)},
+	)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	if len(apps.Items) != 1 {
+		return "", "", microerror.Maskf(notFoundError, "expected 1 chartmuseum app CR labelled %q in namespace %#q, got %d, run bootstrap first", key.PlatformSelector(instance), namespace, len(apps.Items))
+	}
+
+	// chart-operator names the release after the App CR and the chart names
+	// the service after the release.
+	app := apps.Items[0]
+
+	return app.Spec.Namespace, app.Name, nil
+}
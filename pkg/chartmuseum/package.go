@@ -0,0 +1,57 @@
+package chartmuseum
+
+import (
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// PackageChart returns the tarball of the chart at path along with the loaded
+// chart. Chart directories are packaged the same way helm package does,
+// tarballs are loaded to validate them.
+func PackageChart(path string) ([]byte, *chart.Chart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	if !info.IsDir() {
+		c, err := loader.LoadFile(path)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+
+		tarball, err := os.ReadFile(path) // #nosec G304
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+
+		return tarball, c, nil
+	}
+
+	c, err := loader.LoadDir(path)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	dir, err := os.MkdirTemp("", "apptestctl-chart-")
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tarballPath, err := chartutil.Save(c, dir)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	tarball, err := os.ReadFile(tarballPath) // #nosec G304
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return tarball, c, nil
+}
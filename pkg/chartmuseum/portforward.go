@@ -0,0 +1,138 @@
+package chartmuseum
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// forward opens a port-forward through the API server to a ready pod backing
+// the chartmuseum service, the same way kubectl port-forward svc/... does.
+// It returns the local base URL and a func closing the port-forward.
+func (c *Client) forward(ctx context.Context) (string, func(), error) {
+	svc, err := c.k8sClient.CoreV1().Services(c.namespace).Get(ctx, c.service, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return "", nil, microerror.Maskf(executionFailedError, "service '%s/%s' has no ports", c.namespace, c.service)
+	}
+
+	pods, err := c.k8sClient.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		if isPodReady(pods.Items[i]) {
+			pod = &pods.Items[i]
+			break
+		}
+	}
+	if pod == nil {
+		return "", nil, microerror.Maskf(executionFailedError, "no ready pod found for service '%s/%s'", c.namespace, c.service)
+	}
+
+	port, err := targetPort(svc.Spec.Ports[0], *pod)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.restConfig)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	u := c.k8sClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, u)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	errOut := &strings.Builder{}
+
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, errOut)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return "", nil, microerror.Maskf(executionFailedError, "port-forward to pod '%s/%s' failed: %s %s", pod.Namespace, pod.Name, err, errOut.String())
+	case <-ctx.Done():
+		close(stopCh)
+		return "", nil, microerror.Mask(ctx.Err())
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return "", nil, microerror.Mask(err)
+	}
+
+	c.logger.Debugf(ctx, "forwarding 127.0.0.1:%d to pod '%s/%s' port %d", ports[0].Local, pod.Namespace, pod.Name, port)
+
+	stop := func() {
+		close(stopCh)
+	}
+
+	return fmt.Sprintf("http://127.0.0.1:%d", ports[0].Local), stop, nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// targetPort resolves the container port of the pod the service port points
+// to, including named target ports.
+func targetPort(servicePort corev1.ServicePort, pod corev1.Pod) (int32, error) {
+	if servicePort.TargetPort.StrVal == "" {
+		if servicePort.TargetPort.IntVal != 0 {
+			return servicePort.TargetPort.IntVal, nil
+		}
+
+		return servicePort.Port, nil
+	}
+
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == servicePort.TargetPort.StrVal {
+				return port.ContainerPort, nil
+			}
+		}
+	}
+
+	return 0, microerror.Maskf(executionFailedError, "pod '%s/%s' has no port named %#q", pod.Namespace, pod.Name, servicePort.TargetPort.StrVal)
+}
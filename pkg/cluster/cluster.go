@@ -0,0 +1,195 @@
+package cluster
+
+import (
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	inClusterName = "in-cluster"
+)
+
+type Config struct {
+	Flag  *Flag
+	Stdin io.Reader
+}
+
+// Cluster holds the resolved connection details for the target cluster. The
+// kubeconfig is minified to the selected context and flattened, so it can be
+// passed around as a single self-contained string, e.g. to the apptest
+// library.
+type Cluster struct {
+	kubeConfig string
+	restConfig *rest.Config
+}
+
+// New resolves the cluster connection in the same order as kubectl does.
+// An explicit kubeconfig or kubeconfig path wins, followed by KUBECONFIG and
+// the default kubeconfig location. When none of them yields a config the
+// in-cluster service account config is used.
+func New(config Config) (*Cluster, error) {
+	if config.Flag == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Flag must not be empty", config)
+	}
+	if config.Stdin == nil {
+		config.Stdin = os.Stdin
+	}
+
+	err := config.Flag.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	apiConfig, err := loadKubeConfig(config.Flag, config.Stdin)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if apiConfig == nil {
+		apiConfig, err = inClusterKubeConfig()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if config.Flag.Context != "" {
+		if _, ok := apiConfig.Contexts[config.Flag.Context]; !ok {
+			return nil, microerror.Maskf(invalidConfigError, "context %#q not found in kubeconfig", config.Flag.Context)
+		}
+		apiConfig.CurrentContext = config.Flag.Context
+	}
+
+	err = clientcmdapi.MinifyConfig(apiConfig)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%s", err.Error())
+	}
+
+	err = clientcmdapi.FlattenConfig(apiConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if config.Flag.As != "" {
+		for _, authInfo := range apiConfig.AuthInfos {
+			authInfo.Impersonate = config.Flag.As
+			authInfo.ImpersonateGroups = config.Flag.AsGroups
+		}
+	}
+
+	bytes, err := clientcmd.Write(*apiConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(bytes)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c := &Cluster{
+		kubeConfig: string(bytes),
+		restConfig: restConfig,
+	}
+
+	return c, nil
+}
+
+// KubeConfig returns the self-contained kubeconfig for the target cluster.
+func (c *Cluster) KubeConfig() string {
+	return c.kubeConfig
+}
+
+// RESTConfig returns a copy of the REST config for the target cluster.
+func (c *Cluster) RESTConfig() *rest.Config {
+	return rest.CopyConfig(c.restConfig)
+}
+
+// loadKubeConfig returns nil without error when no kubeconfig could be found,
+// so the caller can fall back to the in-cluster config.
+func loadKubeConfig(f *Flag, stdin io.Reader) (*clientcmdapi.Config, error) {
+	if f.KubeConfig == stdinValue || f.KubeConfigPath == stdinValue {
+		bytes, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		apiConfig, err := clientcmd.Load(bytes)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if clientcmdapi.IsConfigEmpty(apiConfig) {
+			return nil, microerror.Maskf(invalidConfigError, "kubeconfig read from stdin must not be empty")
+		}
+
+		return apiConfig, nil
+	}
+
+	if f.KubeConfig != "" {
+		apiConfig, err := clientcmd.Load([]byte(f.KubeConfig))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return apiConfig, nil
+	}
+
+	if f.KubeConfigPath != "" {
+		loadingRules := &clientcmd.ClientConfigLoadingRules{
+			ExplicitPath: f.KubeConfigPath,
+		}
+		apiConfig, err := loadingRules.Load()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return apiConfig, nil
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	apiConfig, err := loadingRules.Load()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if clientcmdapi.IsConfigEmpty(apiConfig) {
+		if os.Getenv(kubeconfigEnvVar) != "" {
+			return nil, microerror.Maskf(invalidConfigError, "kubeconfig referenced by %s must not be empty", kubeconfigEnvVar)
+		}
+
+		return nil, nil
+	}
+
+	return apiConfig, nil
+}
+
+// inClusterKubeConfig builds a kubeconfig from the service account mounted
+// into the pod. The token is referenced by file so that rotated tokens are
+// picked up.
+func inClusterKubeConfig() (*clientcmdapi.Config, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err == rest.ErrNotInCluster {
+		return nil, microerror.Maskf(noKubeConfigError, "either --%s or --%s or %s must be set when not running inside a Kubernetes cluster", kubeconfig, kubeconfigPath, kubeconfigEnvVar)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	apiConfig := clientcmdapi.NewConfig()
+	apiConfig.Clusters[inClusterName] = &clientcmdapi.Cluster{
+		CertificateAuthority: restConfig.CAFile,
+		Server:               restConfig.Host,
+	}
+	apiConfig.AuthInfos[inClusterName] = &clientcmdapi.AuthInfo{
+		TokenFile: restConfig.BearerTokenFile,
+	}
+	apiConfig.Contexts[inClusterName] = &clientcmdapi.Context{
+		AuthInfo: inClusterName,
+		Cluster:  inClusterName,
+	}
+	apiConfig.CurrentContext = inClusterName
+
+	return apiConfig, nil
+}
@@ -0,0 +1,30 @@
+package cluster
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidFlagError = &microerror.Error{
+	Kind: "invalidFlagError",
+}
+
+// IsInvalidFlag asserts invalidFlagError.
+func IsInvalidFlag(err error) bool {
+	return microerror.Cause(err) == invalidFlagError
+}
+
+var noKubeConfigError = &microerror.Error{
+	Kind: "noKubeConfigError",
+}
+
+// IsNoKubeConfig asserts noKubeConfigError.
+func IsNoKubeConfig(err error) bool {
+	return microerror.Cause(err) == noKubeConfigError
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+const (
+	as               = "as"
+	asGroup          = "as-group"
+	instance         = "instance"
+	kubeContext      = "context"
+	kubeconfig       = "kubeconfig"
+	kubeconfigPath   = "kubeconfig-path"
+	kubeconfigEnvVar = "KUBECONFIG"
+	namespaced       = "namespaced"
+
+	// maxInstanceLength keeps the instance suffixed names of namespaces,
+	// releases and the cluster scoped resources of their charts within the
+	// 63 character limit.
+	maxInstanceLength = 20
+
+	// stdinValue is accepted by --kubeconfig and --kubeconfig-path to read
+	// the kubeconfig from stdin.
+	stdinValue = "-"
+)
+
+// Flag holds the cluster connection flags shared by all commands talking to
+// a Kubernetes cluster. They are registered as persistent flags on the root
+// command.
+type Flag struct {
+	As             string
+	AsGroups       []string
+	Context        string
+	KubeConfig     string
+	KubeConfigPath string
+
+	// Instance selects one of several isolated app platforms bootstrapped
+	// into the same cluster. It is empty for the default platform in the
+	// giantswarm namespace.
+	Instance string
+	// Namespaced selects a platform bootstrapped with --namespaced, whose
+	// catalogs and App CRs live in the platform namespace.
+	Namespaced bool
+}
+
+func (f *Flag) Init(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&f.As, as, "", "Username to impersonate for the operation")
+	cmd.PersistentFlags().StringArrayVar(&f.AsGroups, asGroup, nil, "Group to impersonate for the operation, this flag can be repeated to specify multiple groups")
+	cmd.PersistentFlags().StringVar(&f.Context, kubeContext, "", "Name of the kubeconfig context to use")
+	cmd.PersistentFlags().StringVar(&f.Instance, instance, "", "ID of an isolated app platform in the cluster, defaults to the platform in the giantswarm namespace")
+	cmd.PersistentFlags().StringVarP(&f.KubeConfig, kubeconfig, "k", "", "Explicit kubeconfig for the target cluster, use - to read it from stdin")
+	cmd.PersistentFlags().BoolVar(&f.Namespaced, namespaced, false, "The app platform only has namespaced resources, for users without cluster-admin. Its catalogs and App CRs live in the platform namespace.")
+	cmd.PersistentFlags().StringVarP(&f.KubeConfigPath, kubeconfigPath, "p", "", "Path to a kubeconfig file for the target cluster, use - to read it from stdin")
+}
+
+// DefaultAppCRNamespace sets namespace to the App CR namespace of the
+// selected app platform unless the command's flag with the given name was
+// set. App CRs of an isolated or namespaced platform must be in its
+// namespace, which is only known once the persistent --instance and
+// --namespaced flags are parsed, so commands call it from their runner.
+func (f *Flag) DefaultAppCRNamespace(cmd *cobra.Command, name string, namespace *string) {
+	if cmd.Flags().Changed(name) {
+		return
+	}
+
+	*namespace = key.AppCRNamespace(f.Instance, f.Namespaced)
+}
+
+func (f *Flag) Validate() error {
+	if f.KubeConfig != "" && f.KubeConfigPath != "" {
+		return microerror.Maskf(invalidFlagError, "both --%s or --%s must not be set", kubeconfig, kubeconfigPath)
+	}
+	if len(f.AsGroups) > 0 && f.As == "" {
+		return microerror.Maskf(invalidFlagError, "--%s requires --%s to be set", asGroup, as)
+	}
+	if f.Instance != "" {
+		errs := validation.IsDNS1123Label(f.Instance)
+		if len(errs) > 0 {
+			return microerror.Maskf(invalidFlagError, "--%s must be a valid DNS label: %s", instance, strings.Join(errs, ", "))
+		}
+		if len(f.Instance) > maxInstanceLength {
+			return microerror.Maskf(invalidFlagError, "--%s must not be longer than %d characters", instance, maxInstanceLength)
+		}
+	}
+
+	return nil
+}
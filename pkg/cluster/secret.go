@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+// FromKubeConfigSecret returns the cluster of a kubeconfig Secret, like the
+// ones App CRs of workload clusters reference via Spec.KubeConfig.Secret.
+// The kubeconfig bootstrap stored for apptestctl is preferred over the one
+// app-operator uses from inside the management cluster, which may point to
+// an address only reachable from there. kubeContext selects the context,
+// the current one is used when it is empty.
+func FromKubeConfigSecret(ctx context.Context, k8sClient kubernetes.Interface, namespace, name, kubeContext string) (*Cluster, error) {
+	secret, err := k8sClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var kubeConfig []byte
+	for _, k := range []string{key.WorkloadClusterKubeConfigKey, "kubeConfig", "value"} {
+		if len(secret.Data[k]) > 0 {
+			kubeConfig = secret.Data[k]
+			break
+		}
+	}
+	if kubeConfig == nil {
+		return nil, microerror.Maskf(invalidConfigError, "secret '%s/%s' holds no kubeconfig", namespace, name)
+	}
+
+	c := Config{
+		Flag: &Flag{
+			Context:    kubeContext,
+			KubeConfig: string(kubeConfig),
+		},
+	}
+
+	cluster, err := New(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return cluster, nil
+}
@@ -1,78 +1,82 @@
 package crds
 
 import (
-	_ "embed"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/giantswarm/microerror"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
-//go:embed appcatalogentries.yaml
-var appCatalogEntries string
-
-//go:embed appcatalogs.yaml
-var appCatalogs string
-
-//go:embed apps.yaml
-var apps string
-
-//go:embed catalogs.yaml
-var catalogs string
-
-//go:embed charts.yaml
-var charts string
-
-//go:embed ciliumclusterwidenetworkpolicies.yaml
-var ciliumClusterwideNetworkPolicies string
-
-//go:embed ciliumnetworkpolicies.yaml
-var ciliumNetworkPolicies string
-
-//go:embed servicemonitors.yaml
-var serviceMonitors string
-
-//go:embed podmonitors.yaml
-var podMonitors string
-
-//go:embed prometheuses.yaml
-var prometheuses string
+// The YAML manifests in this directory are synced with hack/sync-crds.sh.
+// They are validated and compressed into data by hack/gencrds, which also
+// writes the index in zz_generated.crds.go.
+//
+//go:generate go run ../../hack/gencrds
+
+//go:embed data/*.json.gz
+var data embed.FS
+
+// CRD describes an embedded CustomResourceDefinition. The metadata is
+// available without decoding the CRD, which only happens in Decode.
+type CRD struct {
+	Name   string
+	Group  string
+	Plural string
+	// Versions are the served versions.
+	Versions []string
+	// Digest is the SHA-256 of the CRD's JSON.
+	Digest string
+
+	file string
+}
 
-//go:embed prometheusrules.yaml
-var prometheusrules string
+// All returns the embedded CRDs ordered by name.
+func All() []CRD {
+	crds := make([]CRD, len(all))
+	copy(crds, all)
 
-//go:embed verticalpodautoscalers.yaml
-var verticalPodAutoscalers string
+	return crds
+}
 
-//go:embed policyexception.yaml
-var policyException string
+// Decode decompresses and decodes the CRD. Every call returns a new object.
+func (c CRD) Decode() (*apiextensionsv1.CustomResourceDefinition, error) {
+	compressed, err := data.ReadFile(c.file)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-//go:embed clusterpolicies.yaml
-var clusterPolicies string
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-//go:embed remotewrites.yaml
-var remotewrites string
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-//go:embed scaledobjects.yaml
-var scaledObjects string
+	var crd apiextensionsv1.CustomResourceDefinition
+	err = json.Unmarshal(b, &crd)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-//go:embed gateway-api.yaml
-var gatewayAPI string
+	return &crd, nil
+}
 
-func CRDs() []string {
-	return []string{
-		appCatalogEntries,
-		appCatalogs,
-		apps,
-		catalogs,
-		charts,
-		ciliumClusterwideNetworkPolicies,
-		ciliumNetworkPolicies,
-		clusterPolicies,
-		gatewayAPI,
-		serviceMonitors,
-		podMonitors,
-		prometheuses,
-		prometheusrules,
-		verticalPodAutoscalers,
-		policyException,
-		remotewrites,
-		scaledObjects,
+// Digest returns the SHA-256 of the embedded CRD set, which changes whenever
+// a CRD is added, removed or updated.
+func Digest() string {
+	h := sha256.New()
+	for _, crd := range all {
+		_, _ = h.Write([]byte(crd.Digest))
 	}
+
+	return hex.EncodeToString(h.Sum(nil))
 }
@@ -0,0 +1,83 @@
+package crds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apptestctl/pkg/crds/gen"
+)
+
+// Test_All checks every embedded CRD decodes to a valid CRD and matches a
+// regeneration from the YAML manifests, so manifests synced without running
+// go generate are caught.
+func Test_All(t *testing.T) {
+	generated, err := gen.Load(".")
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	all := All()
+	if len(all) != len(generated) {
+		t.Fatalf("expected %d CRDs got %d, run go generate ./pkg/crds", len(generated), len(all))
+	}
+
+	for i, c := range all {
+		t.Run(c.Name, func(t *testing.T) {
+			g := generated[i]
+			if c.Name != g.Name {
+				t.Fatalf("expected %#v got %#v, run go generate ./pkg/crds", g.Name, c.Name)
+			}
+
+			crd, err := c.Decode()
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			versions, err := gen.Validate(c.Name, crd)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			if crd.Name != c.Name || crd.Spec.Group != c.Group || crd.Spec.Names.Plural != c.Plural {
+				t.Fatalf("expected %s %s %s got %s %s %s", c.Name, c.Group, c.Plural, crd.Name, crd.Spec.Group, crd.Spec.Names.Plural)
+			}
+			if !reflect.DeepEqual(versions, c.Versions) {
+				t.Fatalf("expected %#v got %#v", c.Versions, versions)
+			}
+
+			b, err := json.Marshal(crd)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			sum := sha256.Sum256(b)
+			if hex.EncodeToString(sum[:]) != c.Digest {
+				t.Fatalf("expected %#v got %#v", c.Digest, hex.EncodeToString(sum[:]))
+			}
+
+			expected := CRD{
+				Name:     g.Name,
+				Group:    g.Group,
+				Plural:   g.Plural,
+				Versions: g.Versions,
+				Digest:   g.Digest,
+				file:     c.file,
+			}
+			if !reflect.DeepEqual(c, expected) {
+				t.Fatalf("expected %#v got %#v, run go generate ./pkg/crds", expected, c)
+			}
+		})
+	}
+
+	h := sha256.New()
+	for _, g := range generated {
+		_, _ = h.Write([]byte(g.Digest))
+	}
+	expectedDigest := hex.EncodeToString(h.Sum(nil))
+
+	if Digest() != expectedDigest {
+		t.Fatalf("expected %#v got %#v, run go generate ./pkg/crds", expectedDigest, Digest())
+	}
+}
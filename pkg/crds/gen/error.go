@@ -0,0 +1,12 @@
+package gen
+
+import "github.com/giantswarm/microerror"
+
+var invalidCRDError = &microerror.Error{
+	Kind: "invalidCRDError",
+}
+
+// IsInvalidCRD asserts invalidCRDError.
+func IsInvalidCRD(err error) bool {
+	return microerror.Cause(err) == invalidCRDError
+}
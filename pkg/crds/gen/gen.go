@@ -0,0 +1,180 @@
+// Package gen reads and validates the CRD manifests synced into pkg/crds. It
+// is shared by hack/gencrds, which embeds the result, and the pkg/crds tests,
+// which check the embedded CRDs are up to date with the manifests.
+package gen
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// CRD is a validated CRD read from a manifest.
+type CRD struct {
+	Name   string
+	Group  string
+	Plural string
+	// Versions are the served versions.
+	Versions []string
+	// Digest is the SHA-256 of JSON.
+	Digest string
+	// JSON is the CRD without status, as embedded by pkg/crds.
+	JSON []byte
+	// Manifest is the path of the manifest the CRD was read from.
+	Manifest string
+}
+
+// Load reads all CRDs from the YAML manifests in dir, ordered by name. It
+// fails on any document which is not a valid CRD and on CRDs defined more
+// than once.
+func Load(dir string) ([]CRD, error) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sort.Strings(manifests)
+
+	var crds []CRD
+	seen := map[string]string{}
+	for _, manifest := range manifests {
+		documents, err := readDocuments(manifest)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for i, document := range documents {
+			c, err := decode(fmt.Sprintf("document %d of %s", i+1, manifest), document)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			c.Manifest = manifest
+
+			if other, ok := seen[c.Name]; ok {
+				return nil, microerror.Maskf(invalidCRDError, "CRD %s is defined in %s and %s", c.Name, other, manifest)
+			}
+			seen[c.Name] = manifest
+
+			crds = append(crds, c)
+		}
+	}
+
+	sort.Slice(crds, func(i, j int) bool {
+		return crds[i].Name < crds[j].Name
+	})
+
+	return crds, nil
+}
+
+// Validate checks that c is a CRD apptestctl can apply and returns its
+// served versions. source identifies the CRD in errors.
+func Validate(source string, c *apiextensionsv1.CustomResourceDefinition) ([]string, error) {
+	if c.APIVersion != apiextensionsv1.SchemeGroupVersion.String() || c.Kind != "CustomResourceDefinition" {
+		return nil, microerror.Maskf(invalidCRDError, "%s: expected %s CustomResourceDefinition, got %s %s", source, apiextensionsv1.SchemeGroupVersion, c.APIVersion, c.Kind)
+	}
+	if c.Spec.Group == "" || c.Spec.Names.Plural == "" || c.Spec.Names.Kind == "" {
+		return nil, microerror.Maskf(invalidCRDError, "%s: CRD %#q must have a group, plural and kind", source, c.Name)
+	}
+	if c.Name != fmt.Sprintf("%s.%s", c.Spec.Names.Plural, c.Spec.Group) {
+		return nil, microerror.Maskf(invalidCRDError, "%s: CRD %#q must be named %s.%s", source, c.Name, c.Spec.Names.Plural, c.Spec.Group)
+	}
+
+	var served []string
+	var storage int
+	for _, version := range c.Spec.Versions {
+		if version.Served {
+			served = append(served, version.Name)
+		}
+		if version.Storage {
+			storage++
+		}
+	}
+	if len(served) == 0 {
+		return nil, microerror.Maskf(invalidCRDError, "%s: CRD %#q serves no version", source, c.Name)
+	}
+	if storage != 1 {
+		return nil, microerror.Maskf(invalidCRDError, "%s: CRD %#q must have exactly one storage version, got %d", source, c.Name, storage)
+	}
+
+	return served, nil
+}
+
+// readDocuments splits a manifest into its YAML documents, skipping the
+// ones containing only comments, e.g. the source headers of helm template.
+func readDocuments(manifest string) ([][]byte, error) {
+	f, err := os.Open(manifest) // #nosec G304
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+
+	var documents [][]byte
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var content interface{}
+		err = yaml.Unmarshal(document, &content)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if content == nil {
+			continue
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
+// decode validates a YAML document and encodes it as JSON. source identifies
+// the document in errors.
+func decode(source string, document []byte) (CRD, error) {
+	var c apiextensionsv1.CustomResourceDefinition
+	err := yaml.UnmarshalStrict(document, &c)
+	if err != nil {
+		return CRD{}, microerror.Maskf(invalidCRDError, "%s: %s", source, err)
+	}
+
+	served, err := Validate(source, &c)
+	if err != nil {
+		return CRD{}, microerror.Mask(err)
+	}
+
+	// Status is set by the API server and never applied.
+	c.Status = apiextensionsv1.CustomResourceDefinitionStatus{}
+
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return CRD{}, microerror.Mask(err)
+	}
+	sum := sha256.Sum256(bytes)
+
+	decoded := CRD{
+		Name:     c.Name,
+		Group:    c.Spec.Group,
+		Plural:   c.Spec.Names.Plural,
+		Versions: served,
+		Digest:   hex.EncodeToString(sum[:]),
+		JSON:     bytes,
+	}
+
+	return decoded, nil
+}
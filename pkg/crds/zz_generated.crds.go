@@ -0,0 +1,190 @@
+// Code generated by hack/gencrds. DO NOT EDIT.
+
+package crds
+
+var all = []CRD{
+	{
+		Name:     "appcatalogentries.application.giantswarm.io",
+		Group:    "application.giantswarm.io",
+		Plural:   "appcatalogentries",
+		Versions: []string{"v1alpha1"},
+		Digest:   "a563b6b4086e3a9faa17202c82e7934af36b89615e9099eae563199f15dffcf9",
+		file:     "data/appcatalogentries.application.giantswarm.io.json.gz",
+	},
+	{
+		Name:     "appcatalogs.application.giantswarm.io",
+		Group:    "application.giantswarm.io",
+		Plural:   "appcatalogs",
+		Versions: []string{"v1alpha1"},
+		Digest:   "47c923744880adf5e0f8b3aa53e35d8b06ecd4028a3870e77eef70eb957c0561",
+		file:     "data/appcatalogs.application.giantswarm.io.json.gz",
+	},
+	{
+		Name:     "apps.application.giantswarm.io",
+		Group:    "application.giantswarm.io",
+		Plural:   "apps",
+		Versions: []string{"v1alpha1"},
+		Digest:   "1d07e31cef9e71d503ba1c9901db21dd166711836f74a4cb25bbfe5c23aaa4f5",
+		file:     "data/apps.application.giantswarm.io.json.gz",
+	},
+	{
+		Name:     "catalogs.application.giantswarm.io",
+		Group:    "application.giantswarm.io",
+		Plural:   "catalogs",
+		Versions: []string{"v1alpha1"},
+		Digest:   "28228e237c1251831a9cc092de3da8fb8fe8738b23fb2f2011df9ea405bd1ffe",
+		file:     "data/catalogs.application.giantswarm.io.json.gz",
+	},
+	{
+		Name:     "charts.application.giantswarm.io",
+		Group:    "application.giantswarm.io",
+		Plural:   "charts",
+		Versions: []string{"v1alpha1"},
+		Digest:   "9cb8d63cf384b89a795340a9323c333443c83cb57412c4e3319a25855bc5ce6a",
+		file:     "data/charts.application.giantswarm.io.json.gz",
+	},
+	{
+		Name:     "ciliumclusterwidenetworkpolicies.cilium.io",
+		Group:    "cilium.io",
+		Plural:   "ciliumclusterwidenetworkpolicies",
+		Versions: []string{"v2"},
+		Digest:   "fe858b44a8ac1755fc24035d6226620de3277c0d63272dc0d053f710162cabef",
+		file:     "data/ciliumclusterwidenetworkpolicies.cilium.io.json.gz",
+	},
+	{
+		Name:     "ciliumnetworkpolicies.cilium.io",
+		Group:    "cilium.io",
+		Plural:   "ciliumnetworkpolicies",
+		Versions: []string{"v2"},
+		Digest:   "bc229433d5ddecc33c5fb646d81240e1963a392fb851bc2b9ce915c60fa12109",
+		file:     "data/ciliumnetworkpolicies.cilium.io.json.gz",
+	},
+	{
+		Name:     "clusterpolicies.kyverno.io",
+		Group:    "kyverno.io",
+		Plural:   "clusterpolicies",
+		Versions: []string{"v1", "v2beta1"},
+		Digest:   "d0787c5efc09b6636afdbe98f044252971cdb5cb9f750319825181dccce3c368",
+		file:     "data/clusterpolicies.kyverno.io.json.gz",
+	},
+	{
+		Name:     "gatewayclasses.gateway.networking.k8s.io",
+		Group:    "gateway.networking.k8s.io",
+		Plural:   "gatewayclasses",
+		Versions: []string{"v1", "v1beta1"},
+		Digest:   "ca5226fa5d4eff302b64ab4c6a29dbf60bbbd8bc329607f35a61c18a70ebd0c1",
+		file:     "data/gatewayclasses.gateway.networking.k8s.io.json.gz",
+	},
+	{
+		Name:     "gateways.gateway.networking.k8s.io",
+		Group:    "gateway.networking.k8s.io",
+		Plural:   "gateways",
+		Versions: []string{"v1", "v1beta1"},
+		Digest:   "03f49efd5ecc1ff803f45834e7a836ef0e1974e5ec5b4323ef8539e0b06e05ad",
+		file:     "data/gateways.gateway.networking.k8s.io.json.gz",
+	},
+	{
+		Name:     "grpcroutes.gateway.networking.k8s.io",
+		Group:    "gateway.networking.k8s.io",
+		Plural:   "grpcroutes",
+		Versions: []string{"v1"},
+		Digest:   "8158c6fbbf068e316c5f539e087d9b99de6a64201f4801ace25cc07fa454d39e",
+		file:     "data/grpcroutes.gateway.networking.k8s.io.json.gz",
+	},
+	{
+		Name:     "httproutes.gateway.networking.k8s.io",
+		Group:    "gateway.networking.k8s.io",
+		Plural:   "httproutes",
+		Versions: []string{"v1", "v1beta1"},
+		Digest:   "524bbe12836709bf1bcfd150d420071d7c693bb988acbcfa9dd5ee916f584a3d",
+		file:     "data/httproutes.gateway.networking.k8s.io.json.gz",
+	},
+	{
+		Name:     "inferencepools.inference.networking.k8s.io",
+		Group:    "inference.networking.k8s.io",
+		Plural:   "inferencepools",
+		Versions: []string{"v1"},
+		Digest:   "cd18d6b6d122e49fdb24d4ef309734b2884f98af4d8465ef45f71ae43656ce6f",
+		file:     "data/inferencepools.inference.networking.k8s.io.json.gz",
+	},
+	{
+		Name:     "podmonitors.monitoring.coreos.com",
+		Group:    "monitoring.coreos.com",
+		Plural:   "podmonitors",
+		Versions: []string{"v1"},
+		Digest:   "eba9f2702193653ff141b351fbd23b763f2751e98db300d9d4607412d48763c4",
+		file:     "data/podmonitors.monitoring.coreos.com.json.gz",
+	},
+	{
+		Name:     "policyexceptions.kyverno.io",
+		Group:    "kyverno.io",
+		Plural:   "policyexceptions",
+		Versions: []string{"v2", "v2beta1"},
+		Digest:   "a323cf900858a35df1ae8d27b7671d393e282f755bb9b3a17f3666821595d255",
+		file:     "data/policyexceptions.kyverno.io.json.gz",
+	},
+	{
+		Name:     "prometheuses.monitoring.coreos.com",
+		Group:    "monitoring.coreos.com",
+		Plural:   "prometheuses",
+		Versions: []string{"v1"},
+		Digest:   "b71a41e1a95c6a7874f7227943e85dfc5da3a2a47dcd5825264ae912fb0581f0",
+		file:     "data/prometheuses.monitoring.coreos.com.json.gz",
+	},
+	{
+		Name:     "prometheusrules.monitoring.coreos.com",
+		Group:    "monitoring.coreos.com",
+		Plural:   "prometheusrules",
+		Versions: []string{"v1"},
+		Digest:   "0fefec2e7fc133d358e3358b7acd6bfa890a0662a0dc04c6f408d2f1cda9069d",
+		file:     "data/prometheusrules.monitoring.coreos.com.json.gz",
+	},
+	{
+		Name:     "referencegrants.gateway.networking.k8s.io",
+		Group:    "gateway.networking.k8s.io",
+		Plural:   "referencegrants",
+		Versions: []string{"v1beta1"},
+		Digest:   "169f25e1351cebc3b946eae919eaa99646454d6dacbf96c52efa352c322d3587",
+		file:     "data/referencegrants.gateway.networking.k8s.io.json.gz",
+	},
+	{
+		Name:     "remotewrites.monitoring.giantswarm.io",
+		Group:    "monitoring.giantswarm.io",
+		Plural:   "remotewrites",
+		Versions: []string{"v1alpha1"},
+		Digest:   "9592596623c6cae7cf11fe289f76c4de23148a81137712fd45bd8e5c6c248045",
+		file:     "data/remotewrites.monitoring.giantswarm.io.json.gz",
+	},
+	{
+		Name:     "scaledobjects.keda.sh",
+		Group:    "keda.sh",
+		Plural:   "scaledobjects",
+		Versions: []string{"v1alpha1"},
+		Digest:   "b34dfd53078e74d16030251b1ff63654d3321533781606806d77991faae128d5",
+		file:     "data/scaledobjects.keda.sh.json.gz",
+	},
+	{
+		Name:     "servicemonitors.monitoring.coreos.com",
+		Group:    "monitoring.coreos.com",
+		Plural:   "servicemonitors",
+		Versions: []string{"v1"},
+		Digest:   "f8d94f5552498d742afa37ba2db63aba3bc035d6437c4867964895a493ac2b7d",
+		file:     "data/servicemonitors.monitoring.coreos.com.json.gz",
+	},
+	{
+		Name:     "verticalpodautoscalercheckpoints.autoscaling.k8s.io",
+		Group:    "autoscaling.k8s.io",
+		Plural:   "verticalpodautoscalercheckpoints",
+		Versions: []string{"v1", "v1beta2"},
+		Digest:   "628c57a3c77144fbbaa05c13b5ae7cfacda8e82bfd3d61b1db9a0b6fd517a0aa",
+		file:     "data/verticalpodautoscalercheckpoints.autoscaling.k8s.io.json.gz",
+	},
+	{
+		Name:     "verticalpodautoscalers.autoscaling.k8s.io",
+		Group:    "autoscaling.k8s.io",
+		Plural:   "verticalpodautoscalers",
+		Versions: []string{"v1", "v1beta2"},
+		Digest:   "c8922745a3f1e876aaf1e68e11b94e47a4d7b033869b3dbd62ebfb42396b0e8f",
+		file:     "data/verticalpodautoscalers.autoscaling.k8s.io.json.gz",
+	},
+}
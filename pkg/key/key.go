@@ -0,0 +1,249 @@
+// Package key provides the names, namespaces and labels of the resources
+// apptestctl manages. The instance arguments select one of several isolated
+// app platforms in a cluster, see bootstrap --instance. The empty instance
+// is the default platform in the giantswarm namespace and keeps the well
+// known names.
+package key
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/giantswarm/apptestctl/pkg/project"
+)
+
+const (
+	// InstanceLabel is set on all resources of isolated app platforms,
+	// including their namespaces.
+	InstanceLabel = "apptestctl.giantswarm.io/instance"
+	// ManagedByLabel is set to ManagedByValue on all resources apptestctl
+	// creates for the app platform, except the objects rendered by Helm
+	// releases, whose managed-by label Helm owns.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "apptestctl"
+	// ManagedLabel is set to ManagedValue on all resources apptestctl creates
+	// or labels, including the objects rendered by Helm releases. Selectors
+	// match it rather than ManagedByLabel.
+	ManagedLabel = "apptestctl.giantswarm.io/managed"
+	ManagedValue = "true"
+	// PlatformLabel is set to PlatformValue on the resources bootstrap
+	// creates for the app platform itself, which reset keeps.
+	PlatformLabel = "apptestctl.giantswarm.io/platform"
+	PlatformValue = "true"
+	// WorkloadClusterKubeConfigKey is the key of the workload cluster
+	// kubeconfig apptestctl uses in the kubeconfig Secret. app-operator reads
+	// the kubeConfig and value keys, which may hold a kubeconfig only valid
+	// inside the management cluster.
+	WorkloadClusterKubeConfigKey = "apptestctlKubeConfig"
+	// UniqueAppOperatorVersion is the app-operator version label value of
+	// App and Catalog CRs reconciled by the unique app-operator bootstrap
+	// installs.
+	UniqueAppOperatorVersion = "0.0.0"
+	// VersionAnnotation is the apptestctl version that created a resource.
+	VersionAnnotation = "apptestctl.giantswarm.io/version"
+)
+
+// Annotations returns the annotations of all resources apptestctl creates.
+func Annotations() map[string]string {
+	return map[string]string{
+		VersionAnnotation: project.Version(),
+	}
+}
+
+// Labels returns the labels of all resources apptestctl creates for the
+// app platform instance.
+func Labels(instance string) map[string]string {
+	labels := map[string]string{
+		ManagedByLabel: ManagedByValue,
+		ManagedLabel:   ManagedValue,
+	}
+	if instance != "" {
+		labels[InstanceLabel] = instance
+	}
+
+	return labels
+}
+
+// PlatformLabels returns the labels of the resources bootstrap creates for
+// the app platform instance.
+func PlatformLabels(instance string) map[string]string {
+	labels := Labels(instance)
+	labels[PlatformLabel] = PlatformValue
+
+	return labels
+}
+
+// ReleaseLabels returns the labels of the objects rendered by the Helm
+// releases bootstrap installs for the app platform instance. They leave out
+// ManagedByLabel, since Helm checks it for ownership on later upgrades.
+func ReleaseLabels(instance string) map[string]string {
+	labels := PlatformLabels(instance)
+	delete(labels, ManagedByLabel)
+
+	return labels
+}
+
+// PlatformSelector selects the resources bootstrap created for the app
+// platform instance.
+func PlatformSelector(instance string) labels.Selector {
+	requirement, err := labels.NewRequirement(PlatformLabel, selection.Equals, []string{PlatformValue})
+	if err != nil {
+		panic(err)
+	}
+
+	return Selector(instance).Add(*requirement)
+}
+
+// TestSelector selects the resources apptestctl created for tests against
+// the app platform instance, e.g. with app install, but not the platform
+// itself.
+func TestSelector(instance string) labels.Selector {
+	requirement, err := labels.NewRequirement(PlatformLabel, selection.DoesNotExist, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return Selector(instance).Add(*requirement)
+}
+
+// Selector selects the resources apptestctl created for the app platform
+// instance. The default platform does not select the resources of other
+// instances.
+func Selector(instance string) labels.Selector {
+	set := labels.Set(Labels(instance))
+	delete(set, ManagedByLabel)
+	if instance != "" {
+		return set.AsSelector()
+	}
+
+	requirement, err := labels.NewRequirement(InstanceLabel, selection.DoesNotExist, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return set.AsSelector().Add(*requirement)
+}
+
+// AppCRNamespace is the default namespace of App CRs. app-operator of an
+// instance or of a namespaced platform only watches the platform namespace.
+func AppCRNamespace(instance string, namespaced bool) string {
+	if instance == "" && !namespaced {
+		return metav1.NamespaceDefault
+	}
+
+	return Namespace(instance)
+}
+
+// AppOperatorName is the release name of the unique app-operator installed by
+// bootstrap.
+func AppOperatorName(instance string) string {
+	return withInstance("app-operator", instance)
+}
+
+// CatalogNamespace is the namespace of the Catalog CRs bootstrap creates and
+// of their AppCatalogEntry CRs. Namespaced platforms cannot write to the
+// default namespace, so their catalogs live in the platform namespace.
+func CatalogNamespace(instance string, namespaced bool) string {
+	if !namespaced {
+		return metav1.NamespaceDefault
+	}
+
+	return Namespace(instance)
+}
+
+// ChartMuseumCatalogName is the name of the Catalog CR bootstrap creates for
+// the in-cluster chartmuseum. Catalog CRs of all instances share the default
+// namespace, so the name is unique per instance.
+func ChartMuseumCatalogName(instance string) string {
+	return withInstance("chartmuseum", instance)
+}
+
+// ChartMuseumChartCatalogName is the name of the Catalog CR bootstrap installs
+// chartmuseum from. Each instance gets its own one, so it carries the labels
+// of a single instance and is removed with it. With oci the catalog points
+// to an OCI registry instead of the upstream helm repository.
+func ChartMuseumChartCatalogName(instance string, oci bool) string {
+	name := "apptestctl-chartmuseum"
+	if oci {
+		name += "-oci"
+	}
+
+	return withInstance(name, instance)
+}
+
+// ChartMuseumName is the name of the chartmuseum App CR, release and service.
+// It is the same for all instances since they live in the instance
+// namespace.
+func ChartMuseumName() string {
+	return "chartmuseum"
+}
+
+// ChartOperatorName is the release name of chart-operator installed by
+// bootstrap.
+func ChartOperatorName(instance string) string {
+	return withInstance("chart-operator", instance)
+}
+
+// InfoConfigMapName is the name of the ConfigMap bootstrap records the
+// installed versions in, in the platform namespace.
+func InfoConfigMapName() string {
+	return "apptestctl-info"
+}
+
+// LockName is the name of the Lease serializing bootstrap and reset of the
+// app platform instance.
+func LockName(instance string) string {
+	return withInstance("apptestctl", instance)
+}
+
+// LockNamespace is the namespace of the Lease, which must exist before
+// bootstrap writes anything. Namespaced platforms cannot write to the
+// default namespace, so their lease lives in the platform namespace, which
+// a cluster admin created before.
+func LockNamespace(instance string, namespaced bool) string {
+	if !namespaced {
+		return metav1.NamespaceDefault
+	}
+
+	return Namespace(instance)
+}
+
+// Namespace is the namespace the app platform components are installed into.
+func Namespace(instance string) string {
+	return withInstance("giantswarm", instance)
+}
+
+// UserSecretName is the name of the user values Secret of the App CR named
+// crName.
+func UserSecretName(crName string) string {
+	return crName + "-user-secrets"
+}
+
+// UserValuesName is the name of the user values ConfigMap of the App CR
+// named crName. The apptest library names it after the app, so callers of
+// the library use the app name as App CR name.
+func UserValuesName(crName string) string {
+	return crName + "-user-values"
+}
+
+// WorkloadClusterKubeConfigSecretName is the name of the Secret holding the
+// kubeconfig of a workload cluster, following the <cluster>-kubeconfig
+// convention of management clusters.
+func WorkloadClusterKubeConfigSecretName(cluster string) string {
+	return cluster + "-kubeconfig"
+}
+
+// WorkloadClusterNamespace is the management cluster namespace of a workload
+// cluster, holding its kubeconfig Secret and App CRs.
+func WorkloadClusterNamespace(cluster string) string {
+	return cluster
+}
+
+func withInstance(name, instance string) string {
+	if instance == "" {
+		return name
+	}
+
+	return name + "-" + instance
+}
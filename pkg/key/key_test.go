@@ -0,0 +1,107 @@
+package key
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func Test_Selectors(t *testing.T) {
+	testCases := []struct {
+		name             string
+		instance         string
+		labels           map[string]string
+		expectedAll      bool
+		expectedPlatform bool
+		expectedTest     bool
+	}{
+		{
+			name:             "case 0: platform resource of the default platform",
+			labels:           PlatformLabels(""),
+			expectedAll:      true,
+			expectedPlatform: true,
+		},
+		{
+			name:         "case 1: test resource of the default platform",
+			labels:       Labels(""),
+			expectedAll:  true,
+			expectedTest: true,
+		},
+		{
+			name:   "case 2: resource of an instance is not selected for the default platform",
+			labels: Labels("job-42"),
+		},
+		{
+			name:         "case 3: test resource of an instance",
+			instance:     "job-42",
+			labels:       Labels("job-42"),
+			expectedAll:  true,
+			expectedTest: true,
+		},
+		{
+			name:     "case 4: resource of another instance",
+			instance: "job-42",
+			labels:   PlatformLabels("job-43"),
+		},
+		{
+			name:   "case 5: resource not created by apptestctl",
+			labels: map[string]string{"app.kubernetes.io/name": "chartmuseum"},
+		},
+		{
+			name:   "case 6: resource of another tool managed by apptestctl",
+			labels: map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		{
+			name:             "case 7: object rendered by a platform release keeps the Helm managed-by label",
+			labels:           withLabels(ReleaseLabels(""), map[string]string{ManagedByLabel: "Helm"}),
+			expectedAll:      true,
+			expectedPlatform: true,
+		},
+		{
+			name:             "case 8: object rendered by a platform release of an instance",
+			instance:         "job-42",
+			labels:           withLabels(ReleaseLabels("job-42"), map[string]string{ManagedByLabel: "Helm"}),
+			expectedAll:      true,
+			expectedPlatform: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			set := labels.Set(tc.labels)
+
+			all := Selector(tc.instance).Matches(set)
+			if all != tc.expectedAll {
+				t.Fatalf("expected %#v got %#v", tc.expectedAll, all)
+			}
+
+			platform := PlatformSelector(tc.instance).Matches(set)
+			if platform != tc.expectedPlatform {
+				t.Fatalf("expected %#v got %#v", tc.expectedPlatform, platform)
+			}
+
+			test := TestSelector(tc.instance).Matches(set)
+			if test != tc.expectedTest {
+				t.Fatalf("expected %#v got %#v", tc.expectedTest, test)
+			}
+		})
+	}
+}
+
+func Test_ReleaseLabels(t *testing.T) {
+	_, ok := ReleaseLabels("job-42")[ManagedByLabel]
+	if ok {
+		t.Fatalf("expected %#v got %#v", false, ok)
+	}
+}
+
+func withLabels(labels ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, l := range labels {
+		for k, v := range l {
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
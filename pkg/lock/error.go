@@ -0,0 +1,30 @@
+package lock
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var lockTimeoutError = &microerror.Error{
+	Kind: "lockTimeoutError",
+}
+
+// IsLockTimeout asserts lockTimeoutError.
+func IsLockTimeout(err error) bool {
+	return microerror.Cause(err) == lockTimeoutError
+}
+
+var lockLostError = &microerror.Error{
+	Kind: "lockLostError",
+}
+
+// IsLockLost asserts lockLostError.
+func IsLockLost(err error) bool {
+	return microerror.Cause(err) == lockLostError
+}
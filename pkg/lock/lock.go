@@ -0,0 +1,351 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultLeaseDuration is how long a lease is valid without being
+	// renewed, e.g. after the holder was killed.
+	DefaultLeaseDuration = 30 * time.Second
+
+	pollInterval = 2 * time.Second
+)
+
+type Config struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+	// Stdout receives a line when the lock is held by someone else. Nothing
+	// is printed when it is nil.
+	Stdout io.Writer
+
+	// Annotations and Labels are set on the lease.
+	Annotations map[string]string
+	// Holder identifies the process in the lease, e.g. bootstrap on
+	// host/pid. Defaults to Identity().
+	Holder string
+	Labels map[string]string
+	// LeaseDuration defaults to DefaultLeaseDuration.
+	LeaseDuration time.Duration
+	Name          string
+	Namespace     string
+}
+
+// Lock is a coordination.k8s.io Lease serializing apptestctl commands that
+// modify the app platform, e.g. two pipelines bootstrapping one cluster.
+type Lock struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+	stdout    io.Writer
+
+	annotations   map[string]string
+	holder        string
+	labels        map[string]string
+	leaseDuration time.Duration
+	name          string
+	namespace     string
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(config Config) (*Lock, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Holder == "" {
+		config.Holder = Identity()
+	}
+	if config.LeaseDuration == 0 {
+		config.LeaseDuration = DefaultLeaseDuration
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Name must not be empty", config)
+	}
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Namespace must not be empty", config)
+	}
+
+	l := &Lock{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		stdout:    config.Stdout,
+
+		annotations:   config.Annotations,
+		holder:        config.Holder,
+		labels:        config.Labels,
+		leaseDuration: config.LeaseDuration,
+		name:          config.Name,
+		namespace:     config.Namespace,
+	}
+
+	return l, nil
+}
+
+// Identity returns the hostname and process ID of the caller, which is
+// unique enough to tell CI jobs apart.
+func Identity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s/%d", hostname, os.Getpid())
+}
+
+// Acquire blocks until the lease is taken or timeout passes. Leases which
+// were not renewed within their duration are taken over. The lease is
+// renewed in the background until Release is called. The returned context
+// is canceled with a lockLostError cause when the lease is taken by someone
+// else or could not be renewed within its duration, so the work it guards
+// stops.
+func (l *Lock) Acquire(ctx context.Context, timeout time.Duration) (context.Context, error) {
+	l.logger.Debugf(ctx, "acquiring lease '%s/%s' as %#q", l.namespace, l.name, l.holder)
+
+	deadline := time.After(timeout)
+
+	var lastHolder string
+	for {
+		holder, err := l.tryAcquire(ctx)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if holder == "" {
+			break
+		}
+
+		if holder != lastHolder && l.stdout != nil {
+			_, _ = fmt.Fprintf(l.stdout, "waiting for lock '%s/%s' held by %s\n", l.namespace, l.name, holder)
+		}
+		lastHolder = holder
+
+		select {
+		case <-ctx.Done():
+			return nil, microerror.Mask(ctx.Err())
+		case <-deadline:
+			return nil, microerror.Maskf(lockTimeoutError, "lease '%s/%s' is still held by %s after %s", l.namespace, l.name, holder, timeout)
+		case <-time.After(pollInterval):
+		}
+	}
+
+	l.logger.Debugf(ctx, "acquired lease '%s/%s'", l.namespace, l.name)
+
+	lockCtx, lost := context.WithCancelCause(ctx)
+	renewCtx, cancel := context.WithCancel(context.Background())
+
+	l.mutex.Lock()
+	l.cancel = cancel
+	l.done = make(chan struct{})
+	l.mutex.Unlock()
+
+	go l.renew(renewCtx, l.done, lost)
+
+	return lockCtx, nil
+}
+
+// Release stops renewing the lease and deletes it when it is still held by
+// this process. It uses its own context so the lease is also released when
+// the command was interrupted.
+func (l *Lock) Release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.leaseDuration)
+	defer cancel()
+
+	l.mutex.Lock()
+	if l.cancel != nil {
+		l.cancel()
+		<-l.done
+		l.cancel = nil
+	}
+	l.mutex.Unlock()
+
+	leases := l.k8sClient.CoordinationV1().Leases(l.namespace)
+
+	lease, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if holderIdentity(lease) != l.holder {
+		l.logger.Debugf(ctx, "lease '%s/%s' is held by %#q, not releasing it", l.namespace, l.name, holderIdentity(lease))
+		return nil
+	}
+
+	err = leases.Delete(ctx, l.name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{
+			ResourceVersion: &lease.ResourceVersion,
+		},
+	})
+	if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	l.logger.Debugf(ctx, "released lease '%s/%s'", l.namespace, l.name)
+
+	return nil
+}
+
+// tryAcquire creates or takes over the lease and returns the identity of
+// the other holder when that is not possible.
+func (l *Lock) tryAcquire(ctx context.Context) (string, error) {
+	leases := l.k8sClient.CoordinationV1().Leases(l.namespace)
+
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(l.leaseDuration.Seconds())
+
+	lease, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        l.name,
+				Namespace:   l.namespace,
+				Annotations: l.annotations,
+				Labels:      l.labels,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				AcquireTime:          &now,
+				HolderIdentity:       &l.holder,
+				LeaseDurationSeconds: &seconds,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return "another process", nil
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		return "", nil
+	} else if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	holder := holderIdentity(lease)
+	if holder != "" && holder != l.holder && !isExpired(lease) {
+		return describe(lease), nil
+	}
+
+	if holder != "" && holder != l.holder {
+		l.logger.Debugf(ctx, "taking over expired lease '%s/%s' from %#q", l.namespace, l.name, holder)
+	}
+
+	lease.Spec.AcquireTime = &now
+	lease.Spec.HolderIdentity = &l.holder
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return "another process", nil
+	} else if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return "", nil
+}
+
+// renew bumps the renew time of the lease every third of its duration until
+// ctx is canceled. It calls lost when the lease is held by someone else or
+// was not renewed within its duration, after which others may have taken
+// it over.
+func (l *Lock) renew(ctx context.Context, done chan struct{}, lost context.CancelCauseFunc) {
+	defer close(done)
+
+	leases := l.k8sClient.CoordinationV1().Leases(l.namespace)
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(l.leaseDuration / 3):
+		}
+
+		if time.Since(renewed) >= l.leaseDuration {
+			l.lose(lost, fmt.Sprintf("lease '%s/%s' was not renewed for %s", l.namespace, l.name, time.Since(renewed).Round(time.Second)))
+			return
+		}
+
+		lease, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			l.logger.Errorf(ctx, err, "failed to get lease '%s/%s'", l.namespace, l.name)
+			continue
+		}
+		if holderIdentity(lease) != l.holder {
+			l.lose(lost, fmt.Sprintf("lease '%s/%s' is held by %s", l.namespace, l.name, describe(lease)))
+			return
+		}
+
+		now := metav1.NewMicroTime(time.Now())
+		lease.Spec.RenewTime = &now
+
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			l.logger.Errorf(ctx, err, "failed to renew lease '%s/%s'", l.namespace, l.name)
+			continue
+		}
+		renewed = now.Time
+
+		l.logger.Debugf(ctx, "renewed lease '%s/%s'", l.namespace, l.name)
+	}
+}
+
+// lose cancels the context guarded by the lease with a lockLostError cause.
+func (l *Lock) lose(lost context.CancelCauseFunc, reason string) {
+	if l.stdout != nil {
+		_, _ = fmt.Fprintf(l.stdout, "lost lock: %s\n", reason)
+	}
+
+	lost(microerror.Maskf(lockLostError, "%s", reason))
+}
+
+func describe(lease *coordinationv1.Lease) string {
+	if lease.Spec.AcquireTime == nil {
+		return holderIdentity(lease)
+	}
+
+	return fmt.Sprintf("%s since %s", holderIdentity(lease), lease.Spec.AcquireTime.Format(time.RFC3339))
+}
+
+func holderIdentity(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+
+	return *lease.Spec.HolderIdentity
+}
+
+func isExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+
+	return time.Now().After(expiry)
+}
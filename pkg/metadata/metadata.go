@@ -0,0 +1,177 @@
+// Package metadata marks the resources apptestctl creates with the labels and
+// annotations of pkg/key, including resources created by the apptest library
+// and Helm, which do not support custom labels.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/workload"
+)
+
+// Set adds labels and the apptestctl annotations to obj. Existing ones are
+// kept.
+func Set(obj metav1.Object, labels map[string]string) {
+	l := obj.GetLabels()
+	if l == nil {
+		l = map[string]string{}
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	obj.SetLabels(l)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for k, v := range key.Annotations() {
+		annotations[k] = v
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// Patch adds the metadata to an existing object, e.g. one the apptest library
+// created. obj only needs its namespace and name set.
+func Patch(ctx context.Context, ctrlClient client.Client, obj client.Object, labels map[string]string) error {
+	err := ctrlClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	Set(obj, labels)
+
+	err = ctrlClient.Patch(ctx, obj, patch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// PatchApp adds the metadata to an App CR the apptest library created and to
+// its user values ConfigMap, which the library only creates when values are
+// given.
+func PatchApp(ctx context.Context, ctrlClient client.Client, namespace, crName string, labels map[string]string) error {
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: crName},
+	}
+	err := Patch(ctx, ctrlClient, app, labels)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: key.UserValuesName(crName)},
+	}
+	err = Patch(ctx, ctrlClient, configMap, labels)
+	if apierrors.IsNotFound(err) {
+		// fall through
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// PatchRelease adds the metadata to the Secrets Helm stores the revisions of
+// a release in and to the objects rendered by its latest revision. Helm
+// upgrades merge their changes into the live objects, so the labels survive
+// later revisions. labels must not contain key.ManagedByLabel, which Helm
+// sets on the objects it renders and checks for ownership on upgrades.
+func PatchRelease(ctx context.Context, k8sClients k8sclient.Interface, namespace, releaseName string, labels map[string]string) error {
+	secrets := k8sClients.K8sClient().CoreV1().Secrets(namespace)
+
+	list, err := secrets.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s", releaseName),
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for i := range list.Items {
+		secret := &list.Items[i]
+
+		Set(secret, labels)
+
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	latest, err := workload.LatestRelease(ctx, k8sClients.K8sClient(), releaseName, namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	groupResources, err := restmapper.GetAPIGroupResources(k8sClients.K8sClient().Discovery())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      labels,
+			"annotations": key.Annotations(),
+		},
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, manifest := range releaseutil.SplitManifests(latest.Manifest) {
+		var obj unstructured.Unstructured
+		err = yaml.Unmarshal([]byte(manifest), &obj.Object)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if obj.Object == nil || obj.GetKind() == "" {
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		resource := k8sClients.DynClient().Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			objNamespace := obj.GetNamespace()
+			if objNamespace == "" {
+				objNamespace = namespace
+			}
+			_, err = resource.Namespace(objNamespace).Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		} else {
+			_, err = resource.Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		}
+		if apierrors.IsNotFound(err) {
+			// Objects like hook resources may be gone already.
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
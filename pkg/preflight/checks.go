@@ -0,0 +1,495 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/utils/ptr"
+
+	"github.com/giantswarm/apptestctl/pkg/await"
+	"github.com/giantswarm/apptestctl/pkg/key"
+)
+
+const (
+	// minKubernetesVersion is the oldest Kubernetes version the embedded CRDs
+	// and the pinned operator charts are known to work with.
+	minKubernetesVersion = "1.25.0"
+
+	dnsNamespace   = metav1.NamespaceSystem
+	dnsServiceName = "kube-dns"
+
+	// The DNS probe resolves the API server service from a pod in the
+	// default namespace, which exists before bootstrap created the platform
+	// namespace.
+	dnsProbeNamespace = metav1.NamespaceDefault
+	dnsProbeHost      = "kubernetes.default.svc"
+	dnsProbeImage     = "gsoci.azurecr.io/giantswarm/busybox:1.36.1"
+	dnsProbeTimeout   = 2 * time.Minute
+	dnsProbeInterval  = 2 * time.Second
+	// dnsProbeUser is the nobody user, so the pod passes the restricted Pod
+	// Security Standard.
+	dnsProbeUser = 65534
+
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// permission is a single verb on a resource that bootstrap needs. An empty
+// namespace means the check is done cluster wide.
+type permission struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+	Namespace   string
+}
+
+func (p permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource = fmt.Sprintf("%s/%s", p.Resource, p.Subresource)
+	}
+	if p.Group != "" {
+		resource = fmt.Sprintf("%s.%s", resource, p.Group)
+	}
+
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s", p.Verb, resource)
+	}
+
+	return fmt.Sprintf("%s %s in namespace %#q", p.Verb, resource, p.Namespace)
+}
+
+// requiredPermissions returns the permissions needed to create, wait for
+// and roll back the CRDs, PriorityClass, Namespace, Catalog and chartmuseum
+// resources, the ones helm needs to install, label and uninstall the
+// operator charts and store releases, and the ones of the lock and the DNS
+// check.
+func (p *Preflight) requiredPermissions() []permission {
+	if p.namespaced {
+		return p.requiredNamespacedPermissions()
+	}
+
+	var permissions []permission
+	permissions = append(permissions, verbs("apiextensions.k8s.io", "customresourcedefinitions", "", "create", "get", "list", "watch", "delete")...)
+	permissions = append(permissions, verbs("scheduling.k8s.io", "priorityclasses", "", "create", "delete")...)
+	permissions = append(permissions, verbs("", "namespaces", "", "create", "get", "list", "watch", "delete")...)
+	permissions = append(permissions, verbs("rbac.authorization.k8s.io", "clusterroles", "", "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("rbac.authorization.k8s.io", "clusterrolebindings", "", "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("application.giantswarm.io", "catalogs", metav1.NamespaceDefault, "create", "get", "patch", "delete")...)
+	permissions = append(permissions, verbs("application.giantswarm.io", "appcatalogentries", metav1.NamespaceDefault, "list", "watch")...)
+	permissions = append(permissions, p.requiredPlatformPermissions()...)
+	// The lease of bootstraps which may create the platform namespace
+	// lives in the default namespace.
+	permissions = append(permissions, verbs("coordination.k8s.io", "leases", metav1.NamespaceDefault, "create", "get", "update", "delete")...)
+	// The DNS check resolves service names in a short-lived pod.
+	permissions = append(permissions, verbs("", "pods", dnsProbeNamespace, "create", "get", "watch", "delete")...)
+	permissions = append(permissions, verbs("", "pods/log", dnsProbeNamespace, "get")...)
+
+	return permissions
+}
+
+// requiredNamespacedPermissions returns the permissions bootstrap
+// --namespaced needs, which only writes to the platform namespace and
+// installs the operators with Roles and RoleBindings.
+func (p *Preflight) requiredNamespacedPermissions() []permission {
+	var permissions []permission
+	permissions = append(permissions, verbs("rbac.authorization.k8s.io", "roles", p.namespace, "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("rbac.authorization.k8s.io", "rolebindings", p.namespace, "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("application.giantswarm.io", "catalogs", p.namespace, "create", "get", "patch", "delete")...)
+	permissions = append(permissions, verbs("application.giantswarm.io", "appcatalogentries", p.namespace, "list", "watch")...)
+	permissions = append(permissions, p.requiredPlatformPermissions()...)
+	permissions = append(permissions, verbs("coordination.k8s.io", "leases", p.namespace, "create", "get", "update", "delete")...)
+
+	return permissions
+}
+
+// requiredPlatformPermissions returns the permissions needed in the platform
+// namespace, where the operator releases and chartmuseum live, and in the
+// namespace of the chartmuseum App CR.
+func (p *Preflight) requiredPlatformPermissions() []permission {
+	var permissions []permission
+	permissions = append(permissions, verbs("application.giantswarm.io", "apps", p.appCRNamespace, "create", "get", "patch", "delete")...)
+	if p.appCRNamespace != p.namespace {
+		permissions = append(permissions, verbs("", "configmaps", p.appCRNamespace, "create", "get", "patch", "delete")...)
+	}
+	permissions = append(permissions, verbs("apps", "deployments", p.namespace, "create", "get", "list", "watch", "patch", "delete")...)
+	permissions = append(permissions, verbs("networking.k8s.io", "networkpolicies", p.namespace, "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("", "configmaps", p.namespace, "create", "get", "update", "patch", "delete")...)
+	permissions = append(permissions, verbs("", "secrets", p.namespace, "create", "list", "update", "delete")...)
+	permissions = append(permissions, verbs("", "serviceaccounts", p.namespace, "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("", "services", p.namespace, "create", "patch", "delete")...)
+	permissions = append(permissions, verbs("", "pods/log", p.namespace, "get")...)
+
+	return permissions
+}
+
+// verbs returns a permission for each verb on the resource, which may name
+// a subresource like pods/log.
+func verbs(group, resource, namespace string, verbs ...string) []permission {
+	resource, subresource, _ := strings.Cut(resource, "/")
+
+	var permissions []permission
+	for _, verb := range verbs {
+		permissions = append(permissions, permission{Group: group, Resource: resource, Subresource: subresource, Verb: verb, Namespace: namespace})
+	}
+
+	return permissions
+}
+
+func (p *Preflight) checkServerVersion(ctx context.Context) Result {
+	name := "api-server"
+
+	info, err := p.k8sClient.Discovery().ServerVersion()
+	if err != nil {
+		return Result{
+			Name:        name,
+			Message:     fmt.Sprintf("API server is not reachable: %s", err),
+			Remediation: "check that the kubeconfig points to a running cluster and that the API server is reachable from this machine",
+		}
+	}
+
+	current, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return Result{
+			Name:        name,
+			Message:     fmt.Sprintf("server version %#q cannot be parsed: %s", info.GitVersion, err),
+			Remediation: "use a cluster reporting a semantic Kubernetes version",
+		}
+	}
+
+	if current.LessThan(version.MustParseGeneric(minKubernetesVersion)) {
+		return Result{
+			Name:        name,
+			Message:     fmt.Sprintf("server version %s is older than the minimum supported version %s", info.GitVersion, minKubernetesVersion),
+			Remediation: fmt.Sprintf("upgrade the cluster to Kubernetes %s or newer", minKubernetesVersion),
+		}
+	}
+
+	return Result{
+		Name:    name,
+		Passed:  true,
+		Message: fmt.Sprintf("reachable, server version %s", info.GitVersion),
+	}
+}
+
+func (p *Preflight) checkPermissions(ctx context.Context) ([]Result, error) {
+	name := "permissions"
+
+	permissions := p.requiredPermissions()
+
+	var results []Result
+	for _, perm := range permissions {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Group:       perm.Group,
+					Resource:    perm.Resource,
+					Subresource: perm.Subresource,
+					Verb:        perm.Verb,
+					Namespace:   perm.Namespace,
+				},
+			},
+		}
+
+		p.logger.Debugf(ctx, "reviewing access to %s", perm)
+
+		review, err := p.k8sClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if !review.Status.Allowed {
+			message := fmt.Sprintf("not allowed to %s", perm)
+			if review.Status.Reason != "" {
+				message = fmt.Sprintf("%s: %s", message, review.Status.Reason)
+			}
+
+			results = append(results, Result{
+				Name:        name,
+				Message:     message,
+				Remediation: fmt.Sprintf("grant the caller permission to %s, e.g. by binding the cluster-admin ClusterRole", perm),
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, Result{
+			Name:    name,
+			Passed:  true,
+			Message: fmt.Sprintf("all %d required permissions are granted", len(permissions)),
+		})
+	}
+
+	return results, nil
+}
+
+// checkDefaultStorageClass ensures the PersistentVolumeClaim created by
+// chartmuseum with persistence enabled can be bound.
+func (p *Preflight) checkDefaultStorageClass(ctx context.Context) ([]Result, error) {
+	name := "storage-class"
+
+	list, err := p.k8sClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var defaults []string
+	for _, sc := range list.Items {
+		if sc.Annotations[defaultStorageClassAnnotation] == "true" || sc.Annotations[betaDefaultStorageClassAnnotation] == "true" {
+			defaults = append(defaults, sc.Name)
+		}
+	}
+
+	if len(defaults) == 0 {
+		result := Result{
+			Name:        name,
+			Message:     "no default StorageClass found, chartmuseum's PersistentVolumeClaim will stay pending",
+			Remediation: fmt.Sprintf("mark a StorageClass as default with the %s=true annotation or install a provisioner like local-path-provisioner", defaultStorageClassAnnotation),
+		}
+
+		return []Result{result}, nil
+	}
+
+	result := Result{
+		Name:    name,
+		Passed:  true,
+		Message: fmt.Sprintf("default StorageClass %s", strings.Join(defaults, ", ")),
+	}
+
+	return []Result{result}, nil
+}
+
+func (p *Preflight) checkNodes(ctx context.Context) ([]Result, error) {
+	name := "nodes"
+
+	list, err := p.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var notReady []string
+	for _, node := range list.Items {
+		if !isNodeReady(node) {
+			notReady = append(notReady, node.Name)
+		}
+	}
+	sort.Strings(notReady)
+
+	ready := len(list.Items) - len(notReady)
+
+	if ready == 0 {
+		result := Result{
+			Name:        name,
+			Message:     fmt.Sprintf("none of the %d nodes are Ready", len(list.Items)),
+			Remediation: "wait for the nodes to become Ready or inspect them with kubectl describe nodes",
+		}
+
+		return []Result{result}, nil
+	}
+
+	message := fmt.Sprintf("%d/%d nodes are Ready", ready, len(list.Items))
+	if len(notReady) > 0 {
+		message = fmt.Sprintf("%s, not Ready: %s", message, strings.Join(notReady, ", "))
+	}
+
+	result := Result{
+		Name:    name,
+		Passed:  true,
+		Message: message,
+	}
+
+	return []Result{result}, nil
+}
+
+// checkDNS verifies in-cluster service names resolve by running a lookup of
+// the API server service in a short-lived pod. Pods of the app platform reach
+// each other and chartmuseum via service names, e.g. the chartmuseum Catalog
+// URL.
+func (p *Preflight) checkDNS(ctx context.Context) ([]Result, error) {
+	name := "dns"
+
+	_, err := p.k8sClient.CoreV1().Services(dnsNamespace).Get(ctx, dnsServiceName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		result := Result{
+			Name:        name,
+			Message:     fmt.Sprintf("service %s/%s not found", dnsNamespace, dnsServiceName),
+			Remediation: "install a cluster DNS provider like CoreDNS exposed via the kube-dns service",
+		}
+
+		return []Result{result}, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "apptestctl-preflight-dns-",
+			Namespace:    dnsProbeNamespace,
+			Labels:       key.Labels(""),
+		},
+		Spec: corev1.PodSpec{
+			ActiveDeadlineSeconds: ptr.To(int64(dnsProbeTimeout.Seconds())),
+			Containers: []corev1.Container{
+				{
+					Name:    "lookup",
+					Image:   dnsProbeImage,
+					Command: []string{"nslookup", dnsProbeHost},
+					SecurityContext: &corev1.SecurityContext{
+						AllowPrivilegeEscalation: ptr.To(false),
+						Capabilities: &corev1.Capabilities{
+							Drop: []corev1.Capability{"ALL"},
+						},
+						ReadOnlyRootFilesystem: ptr.To(true),
+					},
+				},
+			},
+			RestartPolicy: corev1.RestartPolicyNever,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsGroup:   ptr.To(int64(dnsProbeUser)),
+				RunAsNonRoot: ptr.To(true),
+				RunAsUser:    ptr.To(int64(dnsProbeUser)),
+				SeccompProfile: &corev1.SeccompProfile{
+					Type: corev1.SeccompProfileTypeRuntimeDefault,
+				},
+			},
+		},
+	}
+
+	pods := p.k8sClient.CoreV1().Pods(dnsProbeNamespace)
+
+	p.logger.Debugf(ctx, "resolving %#q in a pod", dnsProbeHost)
+
+	pod, err = pods.Create(ctx, pod, metav1.CreateOptions{})
+	if isAdmissionDenied(err) {
+		result := Result{
+			Name:        name,
+			Message:     fmt.Sprintf("admission rejected the pod resolving %#q in namespace %#q: %s", dnsProbeHost, dnsProbeNamespace, err),
+			Remediation: fmt.Sprintf("allow pods complying with the restricted Pod Security Standard in namespace %#q or exempt them from the rejecting admission policy", dnsProbeNamespace),
+		}
+
+		return []Result{result}, nil
+	} else if apierrors.IsForbidden(err) {
+		result := Result{
+			Name:        name,
+			Message:     fmt.Sprintf("cannot create a pod in namespace %#q to resolve %#q: %s", dnsProbeNamespace, dnsProbeHost, err),
+			Remediation: fmt.Sprintf("grant the caller permission to create, get and delete pods in namespace %#q", dnsProbeNamespace),
+		}
+
+		return []Result{result}, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	defer func() {
+		err := pods.Delete(context.WithoutCancel(ctx), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: ptr.To(int64(0))})
+		if err != nil && !apierrors.IsNotFound(err) {
+			p.logger.Debugf(ctx, "failed to delete pod '%s/%s': %s", pod.Namespace, pod.Name, err)
+		}
+	}()
+
+	finished := func(ctx context.Context) (bool, error) {
+		pod, err = pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed, nil
+	}
+
+	watchPod := func(ctx context.Context) (watch.Interface, error) {
+		return pods.Watch(ctx, await.NameSelector(pod.Name))
+	}
+
+	err = await.For(ctx, p.logger, fmt.Sprintf("pod '%s/%s' to finish", pod.Namespace, pod.Name), dnsProbeTimeout, dnsProbeInterval, finished, watchPod)
+	if await.IsTimeoutExceeded(err) {
+		result := Result{
+			Name:        name,
+			Message:     fmt.Sprintf("pod resolving %#q did not finish within %s%s", dnsProbeHost, dnsProbeTimeout, waitingReason(pod)),
+			Remediation: fmt.Sprintf("check that the nodes can pull %#q and run pods in namespace %#q", dnsProbeImage, dnsProbeNamespace),
+		}
+
+		return []Result{result}, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if pod.Status.Phase != corev1.PodSucceeded {
+		result := Result{
+			Name:        name,
+			Message:     fmt.Sprintf("%#q cannot be resolved in-cluster%s", dnsProbeHost, p.podOutput(ctx, pod)),
+			Remediation: fmt.Sprintf("check the DNS pods with kubectl -n %s get pods -l k8s-app=%s", dnsNamespace, dnsServiceName),
+		}
+
+		return []Result{result}, nil
+	}
+
+	result := Result{
+		Name:    name,
+		Passed:  true,
+		Message: fmt.Sprintf("%#q resolves in-cluster", dnsProbeHost),
+	}
+
+	return []Result{result}, nil
+}
+
+// podOutput returns the last line the pod logged, to be appended to a
+// message. Logs are best effort, failures to read them are ignored.
+func (p *Preflight) podOutput(ctx context.Context, pod *corev1.Pod) string {
+	logs, err := p.k8sClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(logs)), "\n")
+	if lines[len(lines)-1] == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(": %s", lines[len(lines)-1])
+}
+
+// isAdmissionDenied returns true when err is a rejection of Pod Security
+// admission or an admission webhook. Both are reported as forbidden like a
+// missing permission and only differ in the message.
+func isAdmissionDenied(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	message := err.Error()
+
+	return strings.Contains(message, "violates PodSecurity") || strings.Contains(message, "admission webhook")
+}
+
+// waitingReason returns why the containers of a pod did not start, e.g.
+// ImagePullBackOff, to be appended to a message.
+func waitingReason(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return fmt.Sprintf(", container is waiting: %s", status.State.Waiting.Reason)
+		}
+	}
+
+	return ""
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
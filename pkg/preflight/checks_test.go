@@ -0,0 +1,115 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_checkDNS_rejectedPod(t *testing.T) {
+	testCases := []struct {
+		name            string
+		createErr       error
+		expectedMessage string
+	}{
+		{
+			name:            "case 0: pod security admission",
+			createErr:       apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "apptestctl-preflight-dns-x", errors.New(`violates PodSecurity "restricted:latest": runAsNonRoot != true`)),
+			expectedMessage: "admission rejected the pod",
+		},
+		{
+			name:            "case 1: admission webhook",
+			createErr:       apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "apptestctl-preflight-dns-x", errors.New(`admission webhook "validation.gatekeeper.sh" denied the request`)),
+			expectedMessage: "admission rejected the pod",
+		},
+		{
+			name:            "case 2: missing permission",
+			createErr:       apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New(`User "dev" cannot create resource "pods" in API group "" in the namespace "default"`)),
+			expectedMessage: "cannot create a pod",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8sClient := fake.NewClientset(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: dnsNamespace, Name: dnsServiceName},
+			})
+
+			var created *corev1.Pod
+			k8sClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				created = action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+				return true, nil, tc.createErr
+			})
+
+			p, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Namespace: "giantswarm",
+			})
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			results, err := p.checkDNS(context.Background())
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			if len(results) != 1 || results[0].Passed {
+				t.Fatalf("expected 1 failed result got %#v", results)
+			}
+			if !strings.Contains(results[0].Message, tc.expectedMessage) {
+				t.Fatalf("expected %#v in %#v", tc.expectedMessage, results[0].Message)
+			}
+
+			if created == nil {
+				t.Fatalf("expected a pod to be created")
+			}
+			podSecurity := created.Spec.SecurityContext
+			if podSecurity == nil || podSecurity.RunAsNonRoot == nil || !*podSecurity.RunAsNonRoot || podSecurity.SeccompProfile == nil || podSecurity.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+				t.Fatalf("expected a restricted pod security context got %#v", podSecurity)
+			}
+			containerSecurity := created.Spec.Containers[0].SecurityContext
+			if containerSecurity == nil || containerSecurity.AllowPrivilegeEscalation == nil || *containerSecurity.AllowPrivilegeEscalation || containerSecurity.Capabilities == nil || len(containerSecurity.Capabilities.Drop) != 1 || containerSecurity.Capabilities.Drop[0] != "ALL" {
+				t.Fatalf("expected a restricted container security context got %#v", containerSecurity)
+			}
+		})
+	}
+}
+
+func Test_requiredPermissions(t *testing.T) {
+	p := &Preflight{appCRNamespace: "default", namespace: "giantswarm"}
+
+	expected := []string{
+		"get pods/log in namespace `default`",
+		"update leases.coordination.k8s.io in namespace `default`",
+		"delete leases.coordination.k8s.io in namespace `default`",
+		"watch customresourcedefinitions.apiextensions.k8s.io",
+		"watch appcatalogentries.application.giantswarm.io in namespace `default`",
+		"get configmaps in namespace `giantswarm`",
+		"delete apps.application.giantswarm.io in namespace `default`",
+		"delete configmaps in namespace `default`",
+		"delete priorityclasses.scheduling.k8s.io",
+	}
+
+	granted := map[string]bool{}
+	for _, perm := range p.requiredPermissions() {
+		granted[perm.String()] = true
+	}
+
+	for _, e := range expected {
+		if !granted[e] {
+			t.Fatalf("expected %#v to be required", e)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Config struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// Namespace is the namespace the app platform components are installed
+	// into.
+	Namespace string
+	// AppCRNamespace is the namespace of the chartmuseum App CR and its user
+	// values ConfigMap. It defaults to Namespace.
+	AppCRNamespace string
+	// Namespaced limits the checks to the namespaced resources bootstrap
+	// --namespaced creates. Checks of cluster scoped resources are skipped
+	// since namespace admins usually cannot read them.
+	Namespaced bool
+}
+
+// Preflight checks whether a cluster is suitable for bootstrapping the app
+// platform and whether the caller has all the permissions bootstrap needs.
+type Preflight struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	appCRNamespace string
+	namespace      string
+	namespaced     bool
+}
+
+// Result is the outcome of a single preflight check. Remediation explains how
+// to fix a failed check.
+type Result struct {
+	Name        string
+	Passed      bool
+	Message     string
+	Remediation string
+}
+
+type Results []Result
+
+// Failed returns the results of all failed checks.
+func (rs Results) Failed() Results {
+	var failed Results
+	for _, r := range rs {
+		if !r.Passed {
+			failed = append(failed, r)
+		}
+	}
+
+	return failed
+}
+
+// Print writes a human readable report of the results to w.
+func (rs Results) Print(w io.Writer) {
+	for _, r := range rs {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+
+		_, _ = fmt.Fprintf(w, "[%s] %s: %s\n", status, r.Name, r.Message)
+		if !r.Passed && r.Remediation != "" {
+			_, _ = fmt.Fprintf(w, "       remediation: %s\n", r.Remediation)
+		}
+	}
+}
+
+func New(config Config) (*Preflight, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Namespace must not be empty", config)
+	}
+
+	if config.AppCRNamespace == "" {
+		config.AppCRNamespace = config.Namespace
+	}
+
+	p := &Preflight{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		appCRNamespace: config.AppCRNamespace,
+		namespace:      config.Namespace,
+		namespaced:     config.Namespaced,
+	}
+
+	return p, nil
+}
+
+// Run executes all checks and returns their results. When the API server is
+// not reachable the remaining checks are skipped since they would all fail
+// for the same reason. An error is only returned when a check could not be
+// executed at all.
+func (p *Preflight) Run(ctx context.Context) (Results, error) {
+	var results Results
+
+	result := p.checkServerVersion(ctx)
+	results = append(results, result)
+	if !result.Passed {
+		return results, nil
+	}
+
+	checks := []func(context.Context) ([]Result, error){
+		p.checkPermissions,
+	}
+	if !p.namespaced {
+		checks = append(checks,
+			p.checkDefaultStorageClass,
+			p.checkNodes,
+			p.checkDNS,
+		)
+	}
+
+	for _, check := range checks {
+		rs, err := check(ctx)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		results = append(results, rs...)
+	}
+
+	return results, nil
+}
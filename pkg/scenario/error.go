@@ -0,0 +1,39 @@
+package scenario
+
+import "github.com/giantswarm/microerror"
+
+var assertionFailedError = &microerror.Error{
+	Kind: "assertionFailedError",
+}
+
+// IsAssertionFailed asserts assertionFailedError.
+func IsAssertionFailed(err error) bool {
+	return microerror.Cause(err) == assertionFailedError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidScenarioError = &microerror.Error{
+	Kind: "invalidScenarioError",
+}
+
+// IsInvalidScenario asserts invalidScenarioError.
+func IsInvalidScenario(err error) bool {
+	return microerror.Cause(err) == invalidScenarioError
+}
+
+var timeoutError = &microerror.Error{
+	Kind: "timeoutError",
+}
+
+// IsTimeout asserts timeoutError.
+func IsTimeout(err error) bool {
+	return microerror.Cause(err) == timeoutError
+}
@@ -0,0 +1,165 @@
+package scenario
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Report is the outcome of a scenario run.
+type Report struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"-"`
+	Steps    []StepResult  `json:"steps"`
+}
+
+// StepResult is the outcome of a single step.
+type StepResult struct {
+	Name     string        `json:"name"`
+	Kind     string        `json:"kind"`
+	Passed   bool          `json:"passed"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"-"`
+	Message  string        `json:"message,omitempty"`
+}
+
+// MarshalJSON renders durations like 1m30s instead of nanoseconds.
+func (r Report) MarshalJSON() ([]byte, error) {
+	type alias Report
+	return json.Marshal(struct {
+		alias
+		Duration string `json:"duration"`
+	}{
+		alias:    alias(r),
+		Duration: r.Duration.Round(time.Second).String(),
+	})
+}
+
+// MarshalJSON renders durations like 1m30s instead of nanoseconds.
+func (s StepResult) MarshalJSON() ([]byte, error) {
+	type alias StepResult
+	return json.Marshal(struct {
+		alias
+		Duration string `json:"duration"`
+	}{
+		alias:    alias(s),
+		Duration: s.Duration.Round(time.Second).String(),
+	})
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	if r.Steps == nil {
+		r.Steps = []StepResult{}
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	err := e.Encode(r)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML with one test suite for the
+// scenario and one test case per step, so CI systems can display it.
+func (r Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name: r.Name,
+		Time: junitTime(r.Duration),
+	}
+
+	for _, s := range r.Steps {
+		c := junitTestCase{
+			Name:      s.Name,
+			ClassName: fmt.Sprintf("%s.%s", r.Name, s.Kind),
+			Time:      junitTime(s.Duration),
+		}
+
+		switch {
+		case s.Skipped:
+			c.Skipped = &junitSkipped{Message: s.Message}
+			suite.Skipped++
+		case !s.Passed:
+			c.Failure = &junitFailure{Message: s.Message, Type: s.Kind, Text: s.Message}
+			suite.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+	}
+
+	suites := junitTestSuites{
+		Suites:   []junitTestSuite{suite},
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	err = e.Encode(suites)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = io.WriteString(w, "\n")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
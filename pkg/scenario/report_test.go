@@ -0,0 +1,97 @@
+package scenario
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func Test_Report(t *testing.T) {
+	testCases := []struct {
+		name   string
+		report Report
+		golden string
+	}{
+		{
+			name: "case 0: passed scenario",
+			report: Report{
+				Name:     "hello-world",
+				Passed:   true,
+				Duration: 95 * time.Second,
+				Steps: []StepResult{
+					{Name: "1-pushChart", Kind: "pushChart", Passed: true, Duration: 1500 * time.Millisecond},
+					{Name: "install", Kind: "installApp", Passed: true, Duration: 93500 * time.Millisecond},
+				},
+			},
+			golden: "passed",
+		},
+		{
+			name: "case 1: failed scenario with skipped steps",
+			report: Report{
+				Name:     "hello-world upgrade",
+				Duration: 2 * time.Minute,
+				Steps: []StepResult{
+					{Name: "1-pushChart", Kind: "pushChart", Passed: true, Duration: time.Second},
+					{Name: "2-waitApp", Kind: "waitApp", Duration: 119 * time.Second, Message: `step "2-waitApp" did not finish within 2m0s: app <not> "deployed"`},
+					{Name: "3-deleteApp", Kind: "deleteApp", Skipped: true, Message: "skipped because a previous step failed"},
+				},
+			},
+			golden: "failed",
+		},
+		{
+			name: "case 2: scenario without steps",
+			report: Report{
+				Name:   "empty",
+				Passed: true,
+			},
+			golden: "empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var j bytes.Buffer
+			err := tc.report.WriteJSON(&j)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			assertGolden(t, tc.golden+".json", j.Bytes())
+
+			var x bytes.Buffer
+			err = tc.report.WriteJUnit(&x)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			assertGolden(t, tc.golden+".xml", x.Bytes())
+		})
+	}
+}
+
+// assertGolden compares actual with the golden file testdata/<name>.golden,
+// rewriting it when the tests run with -update.
+func assertGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		err := os.WriteFile(path, actual, 0o600)
+		if err != nil {
+			t.Fatalf("expected %#v got %#v", nil, err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("expected %s got %s", expected, actual)
+	}
+}
@@ -0,0 +1,182 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/giantswarm/apptest"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/chartmuseum"
+)
+
+type Config struct {
+	AppTest     apptest.Interface
+	ChartMuseum *chartmuseum.Client
+	K8sClients  k8sclient.Interface
+	Logger      micrologger.Logger
+	// Stdout receives the progress of the scenario.
+	Stdout io.Writer
+	Waiter *appwait.Waiter
+
+	// Instance is the app platform instance the scenario runs against, see
+	// bootstrap --instance.
+	Instance string
+	// Namespaced is set for platforms bootstrapped with --namespaced.
+	Namespaced bool
+	// Timeout is the timeout of steps when neither the step nor the
+	// scenario sets one.
+	Timeout time.Duration
+}
+
+// Runner executes scenarios against a cluster bootstrapped by apptestctl.
+type Runner struct {
+	appTest     apptest.Interface
+	chartMuseum *chartmuseum.Client
+	k8sClients  k8sclient.Interface
+	logger      micrologger.Logger
+	stdout      io.Writer
+	waiter      *appwait.Waiter
+
+	instance   string
+	namespaced bool
+	timeout    time.Duration
+}
+
+func New(config Config) (*Runner, error) {
+	if config.AppTest == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AppTest must not be empty", config)
+	}
+	if config.ChartMuseum == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartMuseum must not be empty", config)
+	}
+	if config.K8sClients == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClients must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Stdout == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Stdout must not be empty", config)
+	}
+	if config.Waiter == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Waiter must not be empty", config)
+	}
+
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Timeout must be greater than 0", config)
+	}
+
+	r := &Runner{
+		appTest:     config.AppTest,
+		chartMuseum: config.ChartMuseum,
+		k8sClients:  config.K8sClients,
+		logger:      config.Logger,
+		stdout:      config.Stdout,
+		waiter:      config.Waiter,
+
+		instance:   config.Instance,
+		namespaced: config.Namespaced,
+		timeout:    config.Timeout,
+	}
+
+	return r, nil
+}
+
+// Run executes the steps of the scenario in order. Once a step failed all
+// further steps are skipped. Step failures are recorded in the report.
+func (r *Runner) Run(ctx context.Context, s *Scenario) Report {
+	rep := Report{
+		Name:   s.Name,
+		Passed: true,
+	}
+
+	start := time.Now()
+
+	for _, step := range s.Steps {
+		res := StepResult{
+			Name: step.Name,
+			Kind: step.kinds()[0],
+		}
+
+		if !rep.Passed {
+			res.Skipped = true
+			res.Message = "skipped because a previous step failed"
+			rep.Steps = append(rep.Steps, res)
+			continue
+		}
+
+		timeout := stepTimeout(s, step, r.timeout)
+
+		_, _ = fmt.Fprintf(r.stdout, "running step %s\n", step.Name)
+
+		stepStart := time.Now()
+		err := r.runStep(ctx, s, step, timeout)
+		res.Duration = time.Since(stepStart)
+
+		if err != nil {
+			res.Message = microerror.Pretty(err, false)
+			rep.Passed = false
+			_, _ = fmt.Fprintf(r.stdout, "step %s failed after %s: %s\n", step.Name, res.Duration.Round(time.Second), res.Message)
+		} else {
+			res.Passed = true
+			_, _ = fmt.Fprintf(r.stdout, "step %s passed after %s\n", step.Name, res.Duration.Round(time.Second))
+		}
+
+		rep.Steps = append(rep.Steps, res)
+	}
+
+	rep.Duration = time.Since(start)
+
+	return rep
+}
+
+// stepTimeout returns the timeout of the step, which takes precedence over
+// the timeout of the scenario, which in turn takes precedence over
+// defaultTimeout.
+func stepTimeout(s *Scenario, step Step, defaultTimeout time.Duration) time.Duration {
+	if step.Timeout.Duration > 0 {
+		return step.Timeout.Duration
+	}
+	if s.Timeout.Duration > 0 {
+		return s.Timeout.Duration
+	}
+
+	return defaultTimeout
+}
+
+func (r *Runner) runStep(ctx context.Context, s *Scenario, step Step, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case step.AssertAbsent != nil:
+		err = r.assertAbsent(ctx, *step.AssertAbsent)
+	case step.AssertDeployment != nil:
+		err = r.assertDeployment(ctx, *step.AssertDeployment)
+	case step.DeleteApp != nil:
+		err = r.deleteApp(ctx, *step.DeleteApp)
+	case step.InstallApp != nil:
+		err = r.installApp(ctx, s, *step.InstallApp)
+	case step.PushChart != nil:
+		err = r.pushChart(ctx, s, *step.PushChart)
+	case step.UpgradeApp != nil:
+		err = r.upgradeApp(ctx, s, *step.UpgradeApp)
+	case step.WaitApp != nil:
+		err = r.waitApp(ctx, *step.WaitApp)
+	}
+
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return microerror.Maskf(timeoutError, "step %#q did not finish within %s: %s", step.Name, timeout, microerror.Pretty(err, false))
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
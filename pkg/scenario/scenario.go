@@ -0,0 +1,270 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+)
+
+// Scenario is the format of the file passed to apptestctl run, e.g.
+//
+//	name: hello-world upgrade
+//	timeout: 5m
+//	steps:
+//	- pushChart:
+//	    path: ./hello-world-1.0.0.tgz
+//	- installApp:
+//	    name: hello-world
+//	    version: 1.0.0
+//	    namespace: hello
+//	    values:
+//	      replicaCount: 2
+//	- waitApp:
+//	    name: hello-world
+//	    for: deployed
+//	- assertDeployment:
+//	    name: hello-world
+//	    namespace: hello
+//	    readyReplicas: 2
+//	- deleteApp:
+//	    name: hello-world
+//	- assertAbsent:
+//	    apiVersion: apps/v1
+//	    kind: Deployment
+//	    name: hello-world
+//	    namespace: hello
+//	  timeout: 2m
+type Scenario struct {
+	Name string `json:"name"`
+	// Timeout is the default timeout of each step, e.g. 5m.
+	Timeout Duration `json:"timeout,omitempty"`
+	Steps   []Step   `json:"steps"`
+
+	// dir is the directory of the scenario file, relative paths of charts
+	// and values files are resolved against it.
+	dir string
+}
+
+// Step is a single action of a scenario. Exactly one action must be set.
+type Step struct {
+	Name    string   `json:"name,omitempty"`
+	Timeout Duration `json:"timeout,omitempty"`
+
+	AssertAbsent     *AssertAbsent     `json:"assertAbsent,omitempty"`
+	AssertDeployment *AssertDeployment `json:"assertDeployment,omitempty"`
+	DeleteApp        *DeleteApp        `json:"deleteApp,omitempty"`
+	InstallApp       *InstallApp       `json:"installApp,omitempty"`
+	PushChart        *PushChart        `json:"pushChart,omitempty"`
+	UpgradeApp       *UpgradeApp       `json:"upgradeApp,omitempty"`
+	WaitApp          *WaitApp          `json:"waitApp,omitempty"`
+}
+
+// PushChart pushes a chart directory or tarball to the in-cluster
+// chartmuseum.
+type PushChart struct {
+	Path string `json:"path"`
+}
+
+// InstallApp creates an App CR like apptestctl app install.
+type InstallApp struct {
+	Name           string                 `json:"name"`
+	AppCRNamespace string                 `json:"appCRNamespace,omitempty"`
+	Catalog        string                 `json:"catalog,omitempty"`
+	CatalogURL     string                 `json:"catalogURL,omitempty"`
+	Namespace      string                 `json:"namespace,omitempty"`
+	Version        string                 `json:"version"`
+	Values         map[string]interface{} `json:"values,omitempty"`
+	ValuesFile     string                 `json:"valuesFile,omitempty"`
+}
+
+// UpgradeApp bumps the version of an App CR and optionally replaces its user
+// values.
+type UpgradeApp struct {
+	Name           string                 `json:"name"`
+	AppCRNamespace string                 `json:"appCRNamespace,omitempty"`
+	Version        string                 `json:"version"`
+	Values         map[string]interface{} `json:"values,omitempty"`
+	ValuesFile     string                 `json:"valuesFile,omitempty"`
+}
+
+// WaitApp waits for an App CR like apptestctl app wait.
+type WaitApp struct {
+	Name           string `json:"name"`
+	AppCRNamespace string `json:"appCRNamespace,omitempty"`
+	// For is deployed, deleted or version=X.
+	For string `json:"for,omitempty"`
+}
+
+// DeleteApp deletes an App CR and its user values.
+type DeleteApp struct {
+	Name           string `json:"name"`
+	AppCRNamespace string `json:"appCRNamespace,omitempty"`
+}
+
+// AssertDeployment waits until the Deployment has the given number of ready
+// replicas.
+type AssertDeployment struct {
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+}
+
+// AssertAbsent waits until the object does not exist anymore.
+type AssertAbsent struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// Duration is a time.Duration read from strings like 5m.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	d.Duration, err = time.ParseDuration(s)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", d.Duration.String())), nil
+}
+
+// Read reads and validates the scenario at path.
+func Read(path string) (*Scenario, error) {
+	bytes, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var s Scenario
+	err = yaml.UnmarshalStrict(bytes, &s)
+	if err != nil {
+		return nil, microerror.Maskf(invalidScenarioError, "%#q: %s", path, err.Error())
+	}
+
+	s.dir = filepath.Dir(path)
+	if s.Name == "" {
+		s.Name = filepath.Base(path)
+	}
+
+	if len(s.Steps) == 0 {
+		return nil, microerror.Maskf(invalidScenarioError, "%#q: scenario must have at least one step", path)
+	}
+
+	for i := range s.Steps {
+		step := &s.Steps[i]
+
+		kinds := step.kinds()
+		if len(kinds) != 1 {
+			return nil, microerror.Maskf(invalidScenarioError, "%#q: step %d must have exactly one action, found %v", path, i+1, kinds)
+		}
+
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("%d-%s", i+1, kinds[0])
+		}
+
+		problem := step.problem()
+		if problem != "" {
+			return nil, microerror.Maskf(invalidScenarioError, "%#q: step %#q: %s", path, step.Name, problem)
+		}
+	}
+
+	return &s, nil
+}
+
+// kinds returns the names of the actions set in the step.
+func (s Step) kinds() []string {
+	actions := []struct {
+		Kind string
+		Set  bool
+	}{
+		{Kind: "assertAbsent", Set: s.AssertAbsent != nil},
+		{Kind: "assertDeployment", Set: s.AssertDeployment != nil},
+		{Kind: "deleteApp", Set: s.DeleteApp != nil},
+		{Kind: "installApp", Set: s.InstallApp != nil},
+		{Kind: "pushChart", Set: s.PushChart != nil},
+		{Kind: "upgradeApp", Set: s.UpgradeApp != nil},
+		{Kind: "waitApp", Set: s.WaitApp != nil},
+	}
+
+	var kinds []string
+	for _, a := range actions {
+		if a.Set {
+			kinds = append(kinds, a.Kind)
+		}
+	}
+
+	return kinds
+}
+
+// problem describes what is wrong with the action of the step, it is empty
+// for valid steps.
+func (s Step) problem() string {
+	switch {
+	case s.AssertAbsent != nil:
+		a := s.AssertAbsent
+		if a.APIVersion == "" || a.Kind == "" || a.Name == "" {
+			return "apiVersion, kind and name must not be empty"
+		}
+	case s.AssertDeployment != nil:
+		a := s.AssertDeployment
+		if a.Name == "" || a.Namespace == "" {
+			return "name and namespace must not be empty"
+		}
+	case s.DeleteApp != nil:
+		if s.DeleteApp.Name == "" {
+			return "name must not be empty"
+		}
+	case s.InstallApp != nil:
+		a := s.InstallApp
+		if a.Name == "" || a.Version == "" {
+			return "name and version must not be empty"
+		}
+		if a.Values != nil && a.ValuesFile != "" {
+			return "values and valuesFile are mutually exclusive"
+		}
+	case s.PushChart != nil:
+		if s.PushChart.Path == "" {
+			return "path must not be empty"
+		}
+	case s.UpgradeApp != nil:
+		a := s.UpgradeApp
+		if a.Name == "" || a.Version == "" {
+			return "name and version must not be empty"
+		}
+		if a.Values != nil && a.ValuesFile != "" {
+			return "values and valuesFile are mutually exclusive"
+		}
+	case s.WaitApp != nil:
+		if s.WaitApp.Name == "" {
+			return "name must not be empty"
+		}
+		if s.WaitApp.For != "" {
+			_, err := appwait.ParseCondition(s.WaitApp.For)
+			if err != nil {
+				return err.Error()
+			}
+		}
+	}
+
+	return ""
+}
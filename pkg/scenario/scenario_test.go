@@ -0,0 +1,220 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Read(t *testing.T) {
+	testCases := []struct {
+		name            string
+		scenario        string
+		expectedName    string
+		expectedTimeout time.Duration
+		expectedSteps   []string
+		errorMatcher    func(error) bool
+	}{
+		{
+			name: "case 0: valid scenario with defaulted step names",
+			scenario: `name: hello-world upgrade
+timeout: 5m
+steps:
+- pushChart:
+    path: ./hello-world-1.0.0.tgz
+- name: install
+  installApp:
+    name: hello-world
+    version: 1.0.0
+    values:
+      replicaCount: 2
+- waitApp:
+    name: hello-world
+    for: deployed
+  timeout: 90s
+`,
+			expectedName:    "hello-world upgrade",
+			expectedTimeout: 5 * time.Minute,
+			expectedSteps:   []string{"1-pushChart", "install", "3-waitApp"},
+		},
+		{
+			name: "case 1: name defaults to the file name",
+			scenario: `steps:
+- deleteApp:
+    name: hello-world
+`,
+			expectedName:  "scenario.yaml",
+			expectedSteps: []string{"1-deleteApp"},
+		},
+		{
+			name: "case 2: unknown step type",
+			scenario: `steps:
+- restartApp:
+    name: hello-world
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 3: unknown field of a step",
+			scenario: `steps:
+- deleteApp:
+    name: hello-world
+    force: true
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 4: bad scenario timeout",
+			scenario: `timeout: 5 minutes
+steps:
+- deleteApp:
+    name: hello-world
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 5: bad step timeout",
+			scenario: `steps:
+- deleteApp:
+    name: hello-world
+  timeout: 300
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name:         "case 6: no steps",
+			scenario:     "name: empty\n",
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 7: step without action",
+			scenario: `steps:
+- name: nothing
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 8: step with two actions",
+			scenario: `steps:
+- deleteApp:
+    name: hello-world
+  waitApp:
+    name: hello-world
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 9: invalid wait condition",
+			scenario: `steps:
+- waitApp:
+    name: hello-world
+    for: running
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+		{
+			name: "case 10: values and valuesFile",
+			scenario: `steps:
+- installApp:
+    name: hello-world
+    version: 1.0.0
+    values:
+      replicaCount: 2
+    valuesFile: values.yaml
+`,
+			errorMatcher: IsInvalidScenario,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			err := os.WriteFile(path, []byte(tc.scenario), 0o600)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			s, err := Read(path)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if s.Name != tc.expectedName {
+				t.Fatalf("expected %#v got %#v", tc.expectedName, s.Name)
+			}
+			if s.Timeout.Duration != tc.expectedTimeout {
+				t.Fatalf("expected %#v got %#v", tc.expectedTimeout, s.Timeout.Duration)
+			}
+
+			var steps []string
+			for _, step := range s.Steps {
+				steps = append(steps, step.Name)
+			}
+			if !reflect.DeepEqual(steps, tc.expectedSteps) {
+				t.Fatalf("expected %#v got %#v", tc.expectedSteps, steps)
+			}
+		})
+	}
+}
+
+func Test_stepTimeout(t *testing.T) {
+	testCases := []struct {
+		name            string
+		scenarioTimeout time.Duration
+		stepTimeout     time.Duration
+		expectedTimeout time.Duration
+	}{
+		{
+			name:            "case 0: default timeout",
+			expectedTimeout: 10 * time.Minute,
+		},
+		{
+			name:            "case 1: scenario timeout overrides the default",
+			scenarioTimeout: 5 * time.Minute,
+			expectedTimeout: 5 * time.Minute,
+		},
+		{
+			name:            "case 2: step timeout overrides the default",
+			stepTimeout:     2 * time.Minute,
+			expectedTimeout: 2 * time.Minute,
+		},
+		{
+			name:            "case 3: step timeout overrides a shorter scenario timeout",
+			scenarioTimeout: time.Minute,
+			stepTimeout:     2 * time.Minute,
+			expectedTimeout: 2 * time.Minute,
+		},
+		{
+			name:            "case 4: step timeout overrides a longer scenario timeout",
+			scenarioTimeout: 5 * time.Minute,
+			stepTimeout:     30 * time.Second,
+			expectedTimeout: 30 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Scenario{Timeout: Duration{tc.scenarioTimeout}}
+			step := Step{Timeout: Duration{tc.stepTimeout}}
+
+			timeout := stepTimeout(s, step, 10*time.Minute)
+			if timeout != tc.expectedTimeout {
+				t.Fatalf("expected %#v got %#v", tc.expectedTimeout, timeout)
+			}
+		})
+	}
+}
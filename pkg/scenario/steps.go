@@ -0,0 +1,310 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/apptest"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/apptestctl/pkg/appwait"
+	"github.com/giantswarm/apptestctl/pkg/chartmuseum"
+	"github.com/giantswarm/apptestctl/pkg/key"
+	"github.com/giantswarm/apptestctl/pkg/metadata"
+)
+
+const (
+	pollInterval = 2 * time.Second
+)
+
+func (r *Runner) pushChart(ctx context.Context, s *Scenario, a PushChart) error {
+	tarball, chart, err := chartmuseum.PackageChart(s.path(a.Path))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.chartMuseum.Push(ctx, tarball)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "pushed chart %s-%s", chart.Metadata.Name, chart.Metadata.Version)
+
+	return nil
+}
+
+// installApp creates the App CR the same way apptestctl app install does.
+// Existing App CRs are rejected since the apptest library would silently
+// leave them untouched.
+func (r *Runner) installApp(ctx context.Context, s *Scenario, a InstallApp) error {
+	appCRNamespace := r.appCRNamespace(a.AppCRNamespace)
+	catalog := defaultString(a.Catalog, key.ChartMuseumCatalogName(r.instance))
+
+	err := r.k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: appCRNamespace, Name: a.Name}, &v1alpha1.App{})
+	if err == nil {
+		return microerror.Maskf(assertionFailedError, "app CR '%s/%s' already exists", appCRNamespace, a.Name)
+	} else if !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	catalogURL := a.CatalogURL
+	if catalogURL == "" {
+		var cr v1alpha1.Catalog
+		err = r.k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: key.CatalogNamespace(r.instance, r.namespaced), Name: catalog}, &cr)
+		if apierrors.IsNotFound(err) {
+			r.logger.Debugf(ctx, "catalog %#q not found", catalog)
+		} else if err != nil {
+			return microerror.Mask(err)
+		} else {
+			catalogURL = cr.Spec.Storage.URL
+		}
+	}
+
+	valuesYAML, err := s.values(a.Values, a.ValuesFile)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	apps := []apptest.App{
+		{
+			AppCRName:      a.Name,
+			AppCRNamespace: appCRNamespace,
+			CatalogName:    catalog,
+			CatalogURL:     catalogURL,
+			Name:           a.Name,
+			Namespace:      defaultString(a.Namespace, metav1.NamespaceDefault),
+			ValuesYAML:     valuesYAML,
+			Version:        a.Version,
+		},
+	}
+	err = r.appTest.InstallApps(ctx, apps)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = metadata.PatchApp(ctx, r.k8sClients.CtrlClient(), appCRNamespace, a.Name, key.Labels(r.instance))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// upgradeApp bumps the App CR version and replaces the user values when
+// they are set, using the ConfigMap name of the apptest library.
+func (r *Runner) upgradeApp(ctx context.Context, s *Scenario, a UpgradeApp) error {
+	appCRNamespace := r.appCRNamespace(a.AppCRNamespace)
+
+	valuesYAML, err := s.values(a.Values, a.ValuesFile)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var app v1alpha1.App
+	err = r.k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: appCRNamespace, Name: a.Name}, &app)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(assertionFailedError, "app CR '%s/%s' not found", appCRNamespace, a.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	patch := client.MergeFrom(app.DeepCopy())
+
+	if valuesYAML != "" {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.UserValuesName(a.Name),
+				Namespace: appCRNamespace,
+			},
+			Data: map[string]string{
+				"values": valuesYAML,
+			},
+		}
+
+		metadata.Set(configMap, key.Labels(r.instance))
+
+		configMaps := r.k8sClients.K8sClient().CoreV1().ConfigMaps(appCRNamespace)
+
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		app.Spec.UserConfig.ConfigMap.Name = configMap.Name
+		app.Spec.UserConfig.ConfigMap.Namespace = configMap.Namespace
+	}
+
+	app.Spec.Version = a.Version
+
+	err = r.k8sClients.CtrlClient().Patch(ctx, &app, patch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "updated '%s/%s' app cr to version %#q", app.Namespace, app.Name, a.Version)
+
+	return nil
+}
+
+func (r *Runner) waitApp(ctx context.Context, a WaitApp) error {
+	condition, err := appwait.ParseCondition(defaultString(a.For, appwait.DeployedStatus))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.waiter.Wait(ctx, r.appCRNamespace(a.AppCRNamespace), a.Name, condition)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deleteApp deletes the App CR and its user values without waiting, which is
+// what a following waitApp step with for: deleted is for.
+func (r *Runner) deleteApp(ctx context.Context, a DeleteApp) error {
+	appCRNamespace := r.appCRNamespace(a.AppCRNamespace)
+
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      a.Name,
+			Namespace: appCRNamespace,
+		},
+	}
+	err := r.k8sClients.CtrlClient().Delete(ctx, app)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(assertionFailedError, "app CR '%s/%s' not found", appCRNamespace, a.Name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.k8sClients.K8sClient().CoreV1().ConfigMaps(appCRNamespace).Delete(ctx, key.UserValuesName(a.Name), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// assertDeployment polls the Deployment until it has the expected number of
+// ready replicas or the step times out.
+func (r *Runner) assertDeployment(ctx context.Context, a AssertDeployment) error {
+	var last string
+	for {
+		deployment, err := r.k8sClients.K8sClient().AppsV1().Deployments(a.Namespace).Get(ctx, a.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			last = "deployment not found"
+		} else if err != nil && ctx.Err() == nil {
+			return microerror.Mask(err)
+		} else if err == nil {
+			if deployment.Status.ReadyReplicas == a.ReadyReplicas {
+				return nil
+			}
+			last = fmt.Sprintf("%d ready replicas", deployment.Status.ReadyReplicas)
+		}
+
+		r.logger.Debugf(ctx, "deployment '%s/%s' has %s, want %d", a.Namespace, a.Name, last, a.ReadyReplicas)
+
+		select {
+		case <-ctx.Done():
+			return microerror.Maskf(assertionFailedError, "deployment '%s/%s' wants %d ready replicas, last observed %s", a.Namespace, a.Name, a.ReadyReplicas, last)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// assertAbsent polls the object until it is gone or the step times out.
+func (r *Runner) assertAbsent(ctx context.Context, a AssertAbsent) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(a.APIVersion)
+	obj.SetKind(a.Kind)
+
+	for {
+		err := r.k8sClients.CtrlClient().Get(ctx, client.ObjectKey{Namespace: a.Namespace, Name: a.Name}, obj)
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil && ctx.Err() == nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "%s %#q still exists", a.Kind, a.Name)
+
+		select {
+		case <-ctx.Done():
+			return microerror.Maskf(assertionFailedError, "%s '%s/%s' still exists", a.Kind, a.Namespace, a.Name)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// appCRNamespace defaults to the namespace app-operator of the platform
+// watches.
+func (r *Runner) appCRNamespace(namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+
+	return key.AppCRNamespace(r.instance, r.namespaced)
+}
+
+// path resolves p relative to the directory of the scenario file.
+func (s *Scenario) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+
+	return filepath.Join(s.dir, p)
+}
+
+// values returns the user values YAML of inline values or a values file.
+func (s *Scenario) values(values map[string]interface{}, valuesFile string) (string, error) {
+	if values != nil {
+		bytes, err := yaml.Marshal(values)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		return string(bytes), nil
+	}
+
+	if valuesFile == "" {
+		return "", nil
+	}
+
+	path := s.path(valuesFile)
+	bytes, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var v map[string]interface{}
+	err = yaml.Unmarshal(bytes, &v)
+	if err != nil {
+		return "", microerror.Maskf(invalidScenarioError, "%#q is not valid YAML: %s", path, err.Error())
+	}
+
+	return string(bytes), nil
+}
+
+func defaultString(s, d string) string {
+	if s == "" {
+		return d
+	}
+
+	return s
+}
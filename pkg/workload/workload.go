@@ -0,0 +1,209 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	KindDaemonSet   = "DaemonSet"
+	KindDeployment  = "Deployment"
+	KindJob         = "Job"
+	KindStatefulSet = "StatefulSet"
+)
+
+// Workload is a pod controller rendered by a Helm release.
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (w Workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// FromManifest returns the workloads contained in a rendered Helm release
+// manifest. Objects without a namespace are deployed to namespace.
+func FromManifest(manifest, namespace string) ([]Workload, error) {
+	var workloads []Workload
+
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var head struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+
+		err := yaml.Unmarshal([]byte(doc), &head)
+		if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s", err.Error())
+		}
+
+		switch head.Kind {
+		case KindDaemonSet, KindDeployment, KindJob, KindStatefulSet:
+		default:
+			continue
+		}
+
+		w := Workload{
+			Kind:      head.Kind,
+			Namespace: head.Metadata.Namespace,
+			Name:      head.Metadata.Name,
+		}
+		if w.Namespace == "" {
+			w.Namespace = namespace
+		}
+
+		workloads = append(workloads, w)
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].String() < workloads[j].String()
+	})
+
+	return workloads, nil
+}
+
+// FromRelease returns the workloads of the latest revision of the Helm
+// release, read from the release Secrets Helm stores in namespace.
+func FromRelease(ctx context.Context, k8sClient kubernetes.Interface, name, namespace string) ([]Workload, error) {
+	latest, err := LatestRelease(ctx, k8sClient, name, namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	workloads, err := FromManifest(latest.Manifest, namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return workloads, nil
+}
+
+// LatestRelease returns the latest revision of the Helm release, read from
+// the release Secrets Helm stores in namespace.
+func LatestRelease(ctx context.Context, k8sClient kubernetes.Interface, name, namespace string) (*release.Release, error) {
+	secrets := driver.NewSecrets(k8sClient.CoreV1().Secrets(namespace))
+	releases, err := secrets.Query(map[string]string{"name": name, "owner": "helm"})
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, microerror.Maskf(releaseNotFoundError, "release '%s/%s' not found", namespace, name)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	latest := releases[0]
+	for _, r := range releases {
+		if r.Version > latest.Version {
+			latest = r
+		}
+	}
+
+	return latest, nil
+}
+
+// Pods returns the pods selected by the workload's label selector.
+func Pods(ctx context.Context, k8sClient kubernetes.Interface, w Workload) ([]corev1.Pod, error) {
+	var selector *metav1.LabelSelector
+	{
+		switch w.Kind {
+		case KindDaemonSet:
+			o, err := k8sClient.AppsV1().DaemonSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			selector = o.Spec.Selector
+		case KindDeployment:
+			o, err := k8sClient.AppsV1().Deployments(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			selector = o.Spec.Selector
+		case KindJob:
+			o, err := k8sClient.BatchV1().Jobs(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			selector = o.Spec.Selector
+		case KindStatefulSet:
+			o, err := k8sClient.AppsV1().StatefulSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			selector = o.Spec.Selector
+		default:
+			return nil, microerror.Maskf(invalidManifestError, "unsupported workload kind %#q", w.Kind)
+		}
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	list, err := k8sClient.CoreV1().Pods(w.Namespace).List(ctx, metav1.ListOptions{LabelSelector: s.String()})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	return list.Items, nil
+}
+
+// Availability returns the number of available and desired pods of the
+// workload. Jobs report their active pods as available.
+func Availability(ctx context.Context, k8sClient kubernetes.Interface, w Workload) (int32, int32, error) {
+	switch w.Kind {
+	case KindDaemonSet:
+		o, err := k8sClient.AppsV1().DaemonSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, microerror.Mask(err)
+		}
+		return o.Status.NumberAvailable, o.Status.DesiredNumberScheduled, nil
+	case KindDeployment:
+		o, err := k8sClient.AppsV1().Deployments(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, microerror.Mask(err)
+		}
+		return o.Status.AvailableReplicas, replicas(o.Spec.Replicas), nil
+	case KindJob:
+		o, err := k8sClient.BatchV1().Jobs(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, microerror.Mask(err)
+		}
+		return o.Status.Active, replicas(o.Spec.Parallelism), nil
+	case KindStatefulSet:
+		o, err := k8sClient.AppsV1().StatefulSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, microerror.Mask(err)
+		}
+		return o.Status.AvailableReplicas, replicas(o.Spec.Replicas), nil
+	}
+
+	return 0, 0, microerror.Maskf(invalidManifestError, "unsupported workload kind %#q", w.Kind)
+}
+
+// replicas defaults unset replica counts to 1 like the API server does.
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+
+	return *r
+}